@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"encoding/json"
-	"io"
 	"os"
 
 	"github.com/palantir/go-nobadfuncs/nobadfuncs"
@@ -29,6 +28,8 @@ var (
 	rootCmd = &cobra.Command{
 		Use:   "nobadfuncs [flags] [packages]",
 		Short: "verifies that blacklisted functions are not called",
+		// the root command has subcommands, so arbitrary arguments must be allowed explicitly for packages
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			wd, err := os.Getwd()
 			if err != nil {
@@ -39,12 +40,42 @@ var (
 				// if print-all flag is specified, perform print all action
 				return nobadfuncs.PrintAllFuncRefs(args, wd, cmd.OutOrStdout())
 			}
-			return printBadFuncRefsJSONConfig(args, configJSONFlagVal, wd, cmd.OutOrStdout())
+			cfg, err := loadConfig(configFlagVal, configJSONFlagVal)
+			if err != nil {
+				return err
+			}
+			opts := nobadfuncs.Options{
+				NewFromRev:     newFromRevFlagVal,
+				NewFromPatch:   newFromPatchFlagVal,
+				DiscoverConfig: true,
+				CodeOwners:     codeOwnersFlagVal,
+				Format:         nobadfuncs.OutputFormat(outputFormatFlagVal),
+				GroupBy:        nobadfuncs.GroupBy(groupByFlagVal),
+			}
+			// only look for a CODEOWNERS file if the output uses owners so that the file is not loaded otherwise
+			if opts.CodeOwners == "" && opts.UsesOwners() {
+				opts.CodeOwners, _ = nobadfuncs.FindCodeOwners(wd)
+			}
+			return nobadfuncs.PrintBadRefs(args, cfg, wd, opts, cmd.OutOrStdout())
 		},
 	}
 
-	printAllFlagVal   bool
-	configJSONFlagVal string
+	printAllFlagVal     bool
+	configFlagVal       string
+	configJSONFlagVal   string
+	newFromRevFlagVal   string
+	newFromPatchFlagVal string
+	codeOwnersFlagVal   string
+	outputFormatFlagVal string
+	groupByFlagVal      string
+)
+
+const (
+	// configFlagUsage and configJSONFlagUsage are the usage strings of the configuration flags of all commands. All
+	// commands discover configuration files on top of the specified configuration so that the configuration shown by
+	// "config show" is the one that is checked.
+	configFlagUsage     = "path to the YAML configuration file for the check (discovered " + nobadfuncs.ConfigFileName + " files are applied on top of it)"
+	configJSONFlagUsage = "the JSON configuration for the check (discovered " + nobadfuncs.ConfigFileName + " files are applied on top of it)"
 )
 
 func Execute() int {
@@ -53,15 +84,36 @@ func Execute() int {
 
 func init() {
 	rootCmd.Flags().BoolVar(&printAllFlagVal, "print-all", false, "print all function references in the provided package (useful for determining format of forbidden references)")
-	rootCmd.Flags().StringVar(&configJSONFlagVal, "config-json", "", "the JSON configuration for the check")
+	rootCmd.Flags().StringVar(&configFlagVal, "config", "", configFlagUsage)
+	rootCmd.Flags().StringVar(&configJSONFlagVal, "config-json", "", configJSONFlagUsage)
+	rootCmd.Flags().StringVar(&newFromRevFlagVal, "new-from-rev", "", "only report references on lines added or modified relative to the provided git revision")
+	rootCmd.Flags().StringVar(&newFromPatchFlagVal, "new-from-patch", "", "only report references on lines added or modified by the provided unified diff file")
+	rootCmd.Flags().StringVar(&codeOwnersFlagVal, "codeowners", "", "path to the CODEOWNERS file used to determine the owners of references (if not specified and the output includes owners, CODEOWNERS, .github/CODEOWNERS or docs/CODEOWNERS in the repository is used if it exists)")
+	rootCmd.Flags().StringVar(&outputFormatFlagVal, "output-format", string(nobadfuncs.OutputFormatText), "output format of the references (one of text, json, html or sarif)")
+	rootCmd.Flags().StringVar(&groupByFlagVal, "group-by", "", "group the references in the output (one of owner; only supported by the text and json output formats)")
 }
 
-func printBadFuncRefsJSONConfig(pkgs []string, jsonConfig string, dir string, w io.Writer) error {
-	var sigs map[string]string
+// loadConfig returns the configuration specified by the provided configuration file and JSON configuration. The
+// function signatures in the JSON configuration are added to those in the configuration file.
+func loadConfig(configFile, jsonConfig string) (nobadfuncs.Config, error) {
+	var cfg nobadfuncs.Config
+	if configFile != "" {
+		var err error
+		if cfg, err = nobadfuncs.LoadConfig(configFile); err != nil {
+			return nobadfuncs.Config{}, err
+		}
+	}
 	if jsonConfig != "" {
+		var sigs map[string]string
 		if err := json.Unmarshal([]byte(jsonConfig), &sigs); err != nil {
-			return errors.Wrapf(err, "failed to unmarshal configuration as JSON: %q", jsonConfig)
+			return nobadfuncs.Config{}, errors.Wrapf(err, "failed to unmarshal configuration as JSON: %q", jsonConfig)
+		}
+		if cfg.Funcs == nil {
+			cfg.Funcs = make(map[string]string)
+		}
+		for sig, reason := range sigs {
+			cfg.Funcs[sig] = reason
 		}
 	}
-	return nobadfuncs.PrintBadFuncRefs(pkgs, sigs, dir, w)
+	return cfg, nil
 }
@@ -0,0 +1,109 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCmdPackageArgs(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	t.Chdir(projectDir)
+
+	var got bytes.Buffer
+	rootCmd.SetOut(&got)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{"--config-json", `{"func os.Exit(int)": "do not exit"}`, "./..."})
+	defer rootCmd.SetArgs(nil)
+
+	err = rootCmd.Execute()
+	require.Error(t, err)
+	assert.Equal(t, "", err.Error())
+	assert.Equal(t, fmt.Sprintf("%s:6:5: do not exit\n", path.Join(projectDir, "foo/foo.go")), got.String())
+}
+
+func TestRootCmdCodeOwners(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: "CODEOWNERS",
+			Src:     "/ @org/core\n",
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	t.Chdir(projectDir)
+	defer func() {
+		outputFormatFlagVal = "text"
+	}()
+
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SilenceUsage = true
+	defer rootCmd.SetArgs(nil)
+
+	// the CODEOWNERS file is not loaded if the output does not include owners
+	var got bytes.Buffer
+	rootCmd.SetOut(&got)
+	rootCmd.SetArgs([]string{"--config-json", `{"func os.Exit(int)": "do not exit"}`, "--output-format", "text", "./..."})
+	err = rootCmd.Execute()
+	require.Error(t, err)
+	assert.Equal(t, "", err.Error())
+	assert.Equal(t, fmt.Sprintf("%s:6:5: do not exit\n", path.Join(projectDir, "foo/foo.go")), got.String())
+
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"--config-json", `{"func os.Exit(int)": "do not exit"}`, "--output-format", "json", "./..."})
+	err = rootCmd.Execute()
+	assert.EqualError(t, err, fmt.Sprintf(`invalid pattern "/" on line 1 of %s: pattern must not be empty`, path.Join(projectDir, "CODEOWNERS")))
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "inspects the configuration of the check",
+	}
+
+	configShowCmd = &cobra.Command{
+		Use:   "show [flags] <package>",
+		Short: "prints the effective configuration for the provided package, including discovered configuration files",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return errors.Wrapf(err, "failed to determine working directory")
+			}
+			cfg, err := loadConfig(configShowConfigFlagVal, configShowConfigJSONFlagVal)
+			if err != nil {
+				return err
+			}
+			effective, err := nobadfuncs.EffectiveConfig(args[0], cfg, wd, nobadfuncs.Options{
+				DiscoverConfig: true,
+			})
+			if err != nil {
+				return err
+			}
+			enc := yaml.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent(2)
+			if err := enc.Encode(effective); err != nil {
+				return errors.Wrapf(err, "failed to write configuration")
+			}
+			return enc.Close()
+		},
+	}
+
+	configShowConfigFlagVal     string
+	configShowConfigJSONFlagVal string
+)
+
+func init() {
+	configShowCmd.Flags().StringVar(&configShowConfigFlagVal, "config", "", configFlagUsage)
+	configShowCmd.Flags().StringVar(&configShowConfigJSONFlagVal, "config-json", "", configJSONFlagUsage)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,54 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/palantir/go-nobadfuncs/lsp"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lspCmd = &cobra.Command{
+		Use:   "lsp [flags]",
+		Short: "runs a language server on stdin and stdout that publishes diagnostics when Go files are opened or saved",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return errors.Wrapf(err, "failed to determine working directory")
+			}
+			cfg, err := loadConfig(lspConfigFlagVal, lspConfigJSONFlagVal)
+			if err != nil {
+				return err
+			}
+			return lsp.NewServer(cfg, nobadfuncs.Options{
+				DiscoverConfig: true,
+			}).Serve(cmd.InOrStdin(), cmd.OutOrStdout(), wd)
+		},
+	}
+
+	lspConfigFlagVal     string
+	lspConfigJSONFlagVal string
+)
+
+func init() {
+	lspCmd.Flags().StringVar(&lspConfigFlagVal, "config", "", configFlagUsage)
+	lspCmd.Flags().StringVar(&lspConfigJSONFlagVal, "config-json", "", configJSONFlagUsage)
+	rootCmd.AddCommand(lspCmd)
+}
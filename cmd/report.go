@@ -0,0 +1,46 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportCmd = &cobra.Command{
+		Use:   "report [flags] [packages]",
+		Short: "prints an inventory of the external functions, methods, types and variables referenced by the packages",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return errors.Wrapf(err, "failed to determine working directory")
+			}
+			return nobadfuncs.PrintAPIInventory(args, wd, nobadfuncs.InventoryFormat(reportFormatFlagVal), reportIncludeStdFlagVal, cmd.OutOrStdout())
+		},
+	}
+
+	reportFormatFlagVal     string
+	reportIncludeStdFlagVal bool
+)
+
+func init() {
+	reportCmd.Flags().StringVar(&reportFormatFlagVal, "format", string(nobadfuncs.InventoryFormatJSON), "the output format of the report (json or markdown)")
+	reportCmd.Flags().BoolVar(&reportIncludeStdFlagVal, "include-std", false, "include references to the standard library in the report")
+	rootCmd.AddCommand(reportCmd)
+}
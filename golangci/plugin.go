@@ -0,0 +1,72 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package golangci registers nobadfuncs as a golangci-lint module plugin with the name "nobadfuncs". Import this
+// package from the ".custom-gcl.yml" file of a custom golangci-lint build to use it.
+package golangci
+
+import (
+	"os"
+
+	"github.com/golangci/plugin-module-register/register"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+)
+
+func init() {
+	register.Plugin("nobadfuncs", New)
+}
+
+// Settings are the settings of the plugin, which are specified in the "settings" of the "nobadfuncs" custom linter.
+// The settings use the same format as the configuration file and may additionally specify the path of a configuration
+// file, which is included as if it were the first entry of "include".
+type Settings struct {
+	// ConfigFile is the path of a configuration file. A relative path is resolved against the working directory.
+	ConfigFile string `json:"config-file,omitempty"`
+	nobadfuncs.Config
+}
+
+// New returns the plugin for the provided settings.
+func New(rawSettings any) (register.LinterPlugin, error) {
+	settings, err := register.DecodeSettings[Settings](rawSettings)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid nobadfuncs settings")
+	}
+	cfg := settings.Config
+	if settings.ConfigFile != "" {
+		cfg.Include = append([]string{settings.ConfigFile}, cfg.Include...)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine working directory")
+	}
+	analyzer, err := nobadfuncs.NewAnalyzer(cfg, wd)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{analyzer: analyzer}, nil
+}
+
+type plugin struct {
+	analyzer *analysis.Analyzer
+}
+
+func (p *plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{p.analyzer}, nil
+}
+
+func (p *plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
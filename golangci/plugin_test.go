@@ -0,0 +1,248 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golangci_test
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/golangci"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestPlugin(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: "nobadfuncs.yml",
+			Src: `deny:
+  - id: no-exit
+    refs: ["func os.Exit(int)"]
+`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+func Foo() {
+	os.Exit(1)
+	fmt.Println()
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		settings any
+		want     []string
+	}{
+		{
+			name: "inline configuration",
+			settings: map[string]any{
+				"deny": []any{
+					map[string]any{
+						"id":      "no-println",
+						"refs":    []any{"func fmt.Println(...any) (int, error)"},
+						"message": "use the logger instead",
+					},
+				},
+			},
+			want: []string{
+				"foo/foo.go:10:6: no-println: use the logger instead",
+			},
+		},
+		{
+			name: "configuration file and inline configuration",
+			settings: map[string]any{
+				"config-file": path.Join(projectDir, "nobadfuncs.yml"),
+				"funcs": map[string]any{
+					"func fmt.Println(...any) (int, error)": "",
+				},
+			},
+			want: []string{
+				"foo/foo.go:9:5: no-exit: references to \"func os.Exit(int)\" are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.",
+				"foo/foo.go:10:6: : references to \"func fmt.Println(...any) (int, error)\" are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin, err := golangci.New(tc.settings)
+			require.NoError(t, err)
+			assert.Equal(t, "typesinfo", plugin.GetLoadMode())
+
+			analyzers, err := plugin.BuildAnalyzers()
+			require.NoError(t, err)
+			require.Len(t, analyzers, 1)
+
+			assert.Equal(t, tc.want, runAnalyzer(t, analyzers[0], projectDir, "./foo", false))
+		})
+	}
+}
+
+func TestPluginTestFiles(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+}
+`,
+		},
+		{
+			RelPath: "foo/foo_test.go",
+			Src: `package foo
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestFoo(t *testing.T) {
+	os.Exit(1)
+	fmt.Println()
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	plugin, err := golangci.New(map[string]any{
+		"deny": []any{
+			map[string]any{"id": "no-exit", "refs": []any{"os.Exit"}, "message": "do not exit"},
+		},
+		"sequences": []any{
+			map[string]any{"id": "no-print-after-exit", "calls": []any{"fmt.Println"}, "not-after": []any{"os.Exit"}, "message": "unreachable"},
+		},
+	})
+	require.NoError(t, err)
+	analyzers, err := plugin.BuildAnalyzers()
+	require.NoError(t, err)
+	require.Len(t, analyzers, 1)
+
+	// as on the command line, test files are only checked against sequence rules
+	assert.Equal(t, []string{
+		"foo/foo.go:6:5: no-exit: do not exit",
+		"foo/foo_test.go:11:6: no-print-after-exit: unreachable",
+	}, runAnalyzer(t, analyzers[0], projectDir, "./foo", true))
+}
+
+func TestPluginInvalidSettings(t *testing.T) {
+	_, err := golangci.New(map[string]any{
+		"unknown": true,
+	})
+	require.Error(t, err)
+}
+
+func TestPluginUnsupportedSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		settings map[string]any
+		wantErr  string
+	}{
+		{
+			name: "deny rule with versions",
+			settings: map[string]any{
+				"deny": []any{
+					map[string]any{"id": "bug", "refs": []any{"github.com/bar.Parse"}, "versions": "< v1.4.2"},
+				},
+			},
+			wantErr: `deny rule "bug" specifies versions, which are not supported by the analyzer (rules generated from a vulnerability database always specify versions)`,
+		},
+		{
+			name: "deprecated objects",
+			settings: map[string]any{
+				"deprecated": map[string]any{"enabled": true},
+			},
+			wantErr: "deprecated objects are not supported by the analyzer",
+		},
+		{
+			name: "defined types",
+			settings: map[string]any{
+				"match": map[string]any{"defined-types": true},
+			},
+			wantErr: "the defined-types match option is not supported by the analyzer",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := golangci.New(tc.settings)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+// runAnalyzer runs the provided analyzer on the packages matched by the provided pattern (and, if tests is true, their
+// test variants, as golangci-lint does) and returns the diagnostics it reported in the form
+// "file:line:col: category: message", where file is relative to dir.
+func runAnalyzer(t *testing.T, analyzer *analysis.Analyzer, dir, pattern string, tests bool) []string {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo | packages.NeedModule,
+		Dir:   dir,
+		Tests: tests,
+	}, pattern)
+	require.NoError(t, err)
+
+	var got []string
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			// generated main package of the test binary
+			continue
+		}
+		require.Empty(t, pkg.Errors)
+		pass := &analysis.Pass{
+			Analyzer:  analyzer,
+			Fset:      pkg.Fset,
+			Files:     pkg.Syntax,
+			Pkg:       pkg.Types,
+			TypesInfo: pkg.TypesInfo,
+			Module: &analysis.Module{
+				Path: pkg.Module.Path,
+			},
+			Report: func(d analysis.Diagnostic) {
+				pos := pkg.Fset.Position(d.Pos)
+				got = append(got, fmt.Sprintf("%s:%d:%d: %s: %s", pos.Filename[len(dir)+1:], pos.Line, pos.Column, d.Category, d.Message))
+			},
+		}
+		_, err = analyzer.Run(pass)
+		require.NoError(t, err)
+	}
+	return got
+}
@@ -0,0 +1,282 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/pkg/errors"
+)
+
+// okCommentPrefix is the text of the "// OK" comments inserted by code actions. The comment does not include a reason,
+// so it does not whitelist the reference (and the finding is still reported) until the user types the reason after it.
+const okCommentPrefix = "// OK: "
+
+// qualifiedNameRegexp matches replacements that are qualified names, such as "os.ReadFile".
+var qualifiedNameRegexp = regexp.MustCompile(`^([\w./-]+)\.([A-Za-z_]\w*)$`)
+
+// fileLines returns the lines of the file with the provided path.
+func fileLines(filename string) ([]string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filename)
+	}
+	return strings.Split(string(content), "\n"), nil
+}
+
+// newDiagnostic returns the diagnostic for the provided finding. The range of the diagnostic is the identifier at the
+// position of the finding. lines are the lines of the file that contains the finding.
+func newDiagnostic(finding nobadfuncs.Finding, lines []string) diagnostic {
+	line := ""
+	if finding.Pos.Line-1 < len(lines) {
+		line = lines[finding.Pos.Line-1]
+	}
+	start := min(max(finding.Pos.Column-1, 0), len(line))
+	end := start
+	for _, r := range line[start:] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		end += len(string(r))
+	}
+	if end == start {
+		end = len(line)
+	}
+	msg := finding.Message
+	if finding.Via != "" {
+		msg = fmt.Sprintf("%s (low confidence: referenced via %s)", msg, finding.Via)
+	}
+	diag := diagnostic{
+		Range: lspRange{
+			Start: position{Line: finding.Pos.Line - 1, Character: utf16Len(line[:start])},
+			End:   position{Line: finding.Pos.Line - 1, Character: utf16Len(line[:end])},
+		},
+		Severity: severityWarning,
+		Code:     finding.RuleID,
+		Source:   source,
+		Message:  msg,
+	}
+	if finding.Replacement != "" {
+		diag.Data = &diagnosticData{Replacement: finding.Replacement}
+	}
+	return diag
+}
+
+// codeActions returns the code actions for the nobadfuncs diagnostics in the provided parameters.
+func (s *Server) codeActions(params codeActionParams) ([]codeAction, error) {
+	filename, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := fileLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	actions := []codeAction{}
+	for _, diag := range params.Context.Diagnostics {
+		if diag.Source != source || diag.Range.Start.Line >= len(lines) {
+			continue
+		}
+		if diag.Data != nil {
+			if edits, ok := replacementEdits(filename, lines, diag); ok {
+				actions = append(actions, codeAction{
+					Title:       fmt.Sprintf("Replace with %s", diag.Data.Replacement),
+					Kind:        "quickfix",
+					Diagnostics: []diagnostic{diag},
+					Edit:        workspaceEdit{Changes: map[string][]textEdit{params.TextDocument.URI: edits}},
+				})
+			}
+		}
+		line := lines[diag.Range.Start.Line]
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		actions = append(actions, codeAction{
+			Title:       "Whitelist with an \"// OK: [reason]\" comment",
+			Kind:        "quickfix",
+			Diagnostics: []diagnostic{diag},
+			Edit: workspaceEdit{Changes: map[string][]textEdit{params.TextDocument.URI: {{
+				Range:   lspRange{Start: position{Line: diag.Range.Start.Line}, End: position{Line: diag.Range.Start.Line}},
+				NewText: indent + okCommentPrefix + "\n",
+			}}}},
+		})
+	}
+	return actions, nil
+}
+
+// replacementEdits returns the edits that replace the package-qualified reference at the start of the provided
+// diagnostic with its replacement (such as "os.ReadFile"), adding an import for the package of the replacement if
+// necessary and removing the import of the package of the reference if it is not referenced elsewhere in the file.
+// Returns false if the replacement is not a qualified name or if the diagnostic is not at a qualified reference.
+func replacementEdits(filename string, lines []string, diag diagnostic) ([]textEdit, bool) {
+	match := qualifiedNameRegexp.FindStringSubmatch(diag.Data.Replacement)
+	if match == nil {
+		return nil, false
+	}
+	replPath, replName := match[1], match[2]
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, strings.Join(lines, "\n"), parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+	importNames := make(map[string]string)
+	importSpecs := make(map[string]*ast.ImportSpec)
+	importDecls := make(map[*ast.ImportSpec]*ast.GenDecl)
+	var lastImport *ast.GenDecl
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT {
+			continue
+		}
+		lastImport = genDecl
+		for _, spec := range genDecl.Specs {
+			importSpec := spec.(*ast.ImportSpec)
+			importPath, _ := strconv.Unquote(importSpec.Path.Value)
+			name := path.Base(importPath)
+			if importSpec.Name != nil {
+				name = importSpec.Name.Name
+			}
+			importNames[name] = importPath
+			importSpecs[name] = importSpec
+			importDecls[importSpec] = genDecl
+		}
+	}
+
+	var sel *ast.SelectorExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		curr, ok := n.(*ast.SelectorExpr)
+		if !ok || sel != nil {
+			return sel == nil
+		}
+		pos := fset.Position(curr.Sel.Pos())
+		if pos.Line-1 == diag.Range.Start.Line && utf16Len(lines[pos.Line-1][:pos.Column-1]) == diag.Range.Start.Character {
+			if x, ok := curr.X.(*ast.Ident); ok && importNames[x.Name] != "" {
+				sel = curr
+			}
+		}
+		return true
+	})
+	if sel == nil {
+		return nil, false
+	}
+
+	replPkgName := ""
+	for name, importPath := range importNames {
+		if importPath == replPath {
+			replPkgName = name
+		}
+	}
+	pkgName := sel.X.(*ast.Ident).Name
+	pkgUses := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		if curr, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := curr.X.(*ast.Ident); ok && x.Name == pkgName {
+				pkgUses++
+			}
+		}
+		return true
+	})
+	oldSpec := importSpecs[pkgName]
+
+	var edits []textEdit
+	switch {
+	case replPkgName == "" && pkgUses == 1:
+		// replace the import that is no longer used with the import of the replacement so that the edits do not overlap
+		replPkgName = path.Base(replPath)
+		edits = append(edits, textEdit{
+			Range: lspRange{
+				Start: lspPosition(fset.Position(oldSpec.Pos()), lines),
+				End:   lspPosition(fset.Position(oldSpec.End()), lines),
+			},
+			NewText: strconv.Quote(replPath),
+		})
+	case replPkgName == "":
+		replPkgName = path.Base(replPath)
+		edits = append(edits, importEdit(fset, file, lastImport, replPath))
+	case pkgUses == 1:
+		edits = append(edits, removeImportEdit(fset, importDecls[oldSpec], oldSpec))
+	}
+	edits = append(edits, textEdit{
+		Range: lspRange{
+			Start: lspPosition(fset.Position(sel.Pos()), lines),
+			End:   lspPosition(fset.Position(sel.End()), lines),
+		},
+		NewText: replPkgName + "." + replName,
+	})
+	return edits, true
+}
+
+// importEdit returns the edit that adds an import of the provided path to the provided file. lastImport is the last
+// import declaration of the file, if any.
+func importEdit(fset *token.FileSet, file *ast.File, lastImport *ast.GenDecl, importPath string) textEdit {
+	switch {
+	case lastImport != nil && lastImport.Lparen.IsValid():
+		line := fset.Position(lastImport.Lparen).Line
+		return textEdit{
+			Range:   lspRange{Start: position{Line: line}, End: position{Line: line}},
+			NewText: fmt.Sprintf("\t%q\n", importPath),
+		}
+	case lastImport != nil:
+		line := fset.Position(lastImport.End()).Line
+		return textEdit{
+			Range:   lspRange{Start: position{Line: line}, End: position{Line: line}},
+			NewText: fmt.Sprintf("import %q\n", importPath),
+		}
+	default:
+		line := fset.Position(file.Name.End()).Line
+		return textEdit{
+			Range:   lspRange{Start: position{Line: line}, End: position{Line: line}},
+			NewText: fmt.Sprintf("\nimport %q\n", importPath),
+		}
+	}
+}
+
+// removeImportEdit returns the edit that removes the provided import spec of the provided import declaration. The lines
+// of the spec are removed if the declaration is parenthesized and the lines of the declaration are removed otherwise.
+func removeImportEdit(fset *token.FileSet, decl *ast.GenDecl, spec *ast.ImportSpec) textEdit {
+	var node ast.Node = decl
+	if decl.Lparen.IsValid() {
+		node = spec
+	}
+	return textEdit{
+		Range: lspRange{
+			Start: position{Line: fset.Position(node.Pos()).Line - 1},
+			End:   position{Line: fset.Position(node.End()).Line},
+		},
+	}
+}
+
+func lspPosition(pos token.Position, lines []string) position {
+	return position{
+		Line:      pos.Line - 1,
+		Character: utf16Len(lines[pos.Line-1][:pos.Column-1]),
+	}
+}
+
+// utf16Len returns the number of UTF-16 code units in the provided string, which is the unit of the character offsets
+// of the Language Server Protocol.
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
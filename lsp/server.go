@@ -0,0 +1,231 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lsp provides a language server that publishes the findings of nobadfuncs as diagnostics.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/pkg/errors"
+)
+
+const source = "nobadfuncs"
+
+// Server is a language server that checks the package of a Go file when it is opened or saved and publishes the
+// findings as diagnostics. It provides code actions that insert an "// OK: [reason]" comment to whitelist a finding and
+// that replace a reference with the replacement specified by the rule, if the replacement is a qualified name.
+type Server struct {
+	cfg  nobadfuncs.Config
+	opts nobadfuncs.Options
+	root string
+	out  io.Writer
+	// published maps the directory of each checked package to the URIs of the files in the package for which
+	// diagnostics were published.
+	published map[string]map[string]struct{}
+}
+
+// NewServer returns a new server that checks packages using the provided configuration and options.
+func NewServer(cfg nobadfuncs.Config, opts nobadfuncs.Options) *Server {
+	return &Server{
+		cfg:       cfg,
+		opts:      opts,
+		published: make(map[string]map[string]struct{}),
+	}
+}
+
+// Serve reads requests from in and writes responses and notifications to out until the client sends an "exit"
+// notification or in is closed. The root of the workspace is determined by the "initialize" request. If the workspace
+// does not specify a root, dir is used.
+func (s *Server) Serve(in io.Reader, out io.Writer, dir string) error {
+	s.root = dir
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// notifications do not have responses
+			if err != nil {
+				s.logError(err)
+			}
+			continue
+		}
+		resp := message{ID: msg.ID}
+		if err != nil {
+			resp.Error = &responseError{Code: -32603, Message: err.Error()}
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := writeMessage(out, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if params.RootURI != "" {
+			root, err := uriToPath(params.RootURI)
+			if err != nil {
+				return nil, err
+			}
+			s.root = root
+		}
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync: textDocumentSyncOptions{
+					OpenClose: true,
+					Save:      true,
+				},
+				CodeActionProvider: true,
+			},
+			ServerInfo: serverInfo{Name: source},
+		}, nil
+	case "textDocument/didOpen", "textDocument/didSave":
+		var params textDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return nil, s.check(params.TextDocument.URI)
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return s.codeActions(params)
+	case "shutdown":
+		return nil, nil
+	default:
+		if msg.ID != nil {
+			return nil, errors.Errorf("unsupported method %q", msg.Method)
+		}
+		return nil, nil
+	}
+}
+
+// check checks the package that contains the file with the provided URI and publishes the diagnostics for all of the
+// files in the package.
+func (s *Server) check(uri string) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(path, ".go") {
+		return nil
+	}
+	pkgDir := filepath.Dir(path)
+	rel, err := filepath.Rel(s.root, pkgDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to determine package of %s", path)
+	}
+	findings, err := nobadfuncs.Check([]string{"./" + filepath.ToSlash(rel)}, s.cfg, s.root, s.opts)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := make(map[string][]diagnostic)
+	lines := make(map[string][]string)
+	for _, finding := range findings {
+		if filepath.Dir(finding.Pos.Filename) != pkgDir {
+			continue
+		}
+		if _, ok := lines[finding.Pos.Filename]; !ok {
+			if lines[finding.Pos.Filename], err = fileLines(finding.Pos.Filename); err != nil {
+				return err
+			}
+		}
+		findingURI := pathToURI(finding.Pos.Filename)
+		diagnostics[findingURI] = append(diagnostics[findingURI], newDiagnostic(finding, lines[finding.Pos.Filename]))
+	}
+	// clear the diagnostics of files that no longer have findings
+	for prevURI := range s.published[pkgDir] {
+		if _, ok := diagnostics[prevURI]; !ok {
+			diagnostics[prevURI] = []diagnostic{}
+		}
+	}
+	s.published[pkgDir] = make(map[string]struct{})
+	var uris []string
+	for findingURI := range diagnostics {
+		uris = append(uris, findingURI)
+	}
+	sort.Strings(uris)
+	for _, findingURI := range uris {
+		fileDiagnostics := diagnostics[findingURI]
+		if len(fileDiagnostics) > 0 {
+			s.published[pkgDir][findingURI] = struct{}{}
+		}
+		if err := s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         findingURI,
+			Diagnostics: fileDiagnostics,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) notify(method string, params any) error {
+	paramBytes, err := json.Marshal(params)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return writeMessage(s.out, message{
+		Method: method,
+		Params: paramBytes,
+	})
+}
+
+func (s *Server) logError(err error) {
+	_ = s.notify("window/logMessage", logMessageParams{
+		Type:    messageTypeError,
+		Message: err.Error(),
+	})
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid URI %q", uri)
+	}
+	if u.Scheme != "file" {
+		return "", errors.Errorf("unsupported URI %q: only file URIs are supported", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,283 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/lsp"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	prevValue := os.Getenv("GOFLAGS")
+	defer func() {
+		_ = os.Setenv("GOFLAGS", prevValue)
+	}()
+	err := os.Setenv("GOFLAGS", "-mod=mod")
+	require.NoError(t, err)
+
+	projectDir := t.TempDir()
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     "module github.com/palantir/go-nobadfuncs-test",
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import (
+	"io/ioutil"
+)
+
+func Foo() {
+	_, _ = ioutil.ReadFile("foo")
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+	fooURI := "file://" + path.Join(projectDir, "foo/foo.go")
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- lsp.NewServer(nobadfuncs.Config{
+			Deny: []nobadfuncs.Rule{
+				{
+					ID:          "no-ioutil",
+					Refs:        []string{"io/ioutil.*"},
+					Message:     "use {{.Replacement}} instead",
+					Replacement: "os.ReadFile",
+				},
+			},
+		}, nobadfuncs.Options{}).Serve(serverIn, serverOut, projectDir)
+		_ = serverOut.Close()
+	}()
+	r := bufio.NewReader(clientIn)
+
+	send(t, clientOut, 1, "initialize", map[string]any{"rootUri": "file://" + projectDir})
+	initResp := receive(t, r)
+	assert.Equal(t, true, initResp["result"].(map[string]any)["capabilities"].(map[string]any)["codeActionProvider"])
+
+	send(t, clientOut, 0, "textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": fooURI}})
+	published := receive(t, r)
+	assert.Equal(t, "textDocument/publishDiagnostics", published["method"])
+	diagnostics := published["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, map[string]any{
+		"range": map[string]any{
+			"start": map[string]any{"line": float64(7), "character": float64(15)},
+			"end":   map[string]any{"line": float64(7), "character": float64(23)},
+		},
+		"severity": float64(2),
+		"code":     "no-ioutil",
+		"source":   "nobadfuncs",
+		"message":  "use os.ReadFile instead",
+		"data":     map[string]any{"replacement": "os.ReadFile"},
+	}, diagnostics[0])
+
+	send(t, clientOut, 2, "textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": fooURI},
+		"range":        diagnostics[0].(map[string]any)["range"],
+		"context":      map[string]any{"diagnostics": diagnostics},
+	})
+	actions := receive(t, r)["result"].([]any)
+	require.Len(t, actions, 2)
+
+	replace := actions[0].(map[string]any)
+	assert.Equal(t, "Replace with os.ReadFile", replace["title"])
+	// the reference is the last use of the import, so the import is replaced with the import of the replacement
+	assert.Equal(t, []any{
+		map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(3), "character": float64(1)},
+				"end":   map[string]any{"line": float64(3), "character": float64(12)},
+			},
+			"newText": "\"os\"",
+		},
+		map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(7), "character": float64(8)},
+				"end":   map[string]any{"line": float64(7), "character": float64(23)},
+			},
+			"newText": "os.ReadFile",
+		},
+	}, replace["edit"].(map[string]any)["changes"].(map[string]any)[fooURI])
+
+	whitelist := actions[1].(map[string]any)
+	assert.Equal(t, "Whitelist with an \"// OK: [reason]\" comment", whitelist["title"])
+	assert.Equal(t, []any{
+		map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(7), "character": float64(0)},
+				"end":   map[string]any{"line": float64(7), "character": float64(0)},
+			},
+			"newText": "\t// OK: \n",
+		},
+	}, whitelist["edit"].(map[string]any)["changes"].(map[string]any)[fooURI])
+
+	// the import is kept if the package is referenced elsewhere in the file and removed once the last reference is
+	// replaced
+	barPath := path.Join(projectDir, "bar/bar.go")
+	barURI := "file://" + barPath
+	err = os.MkdirAll(path.Dir(barPath), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(barPath, []byte(`package bar
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func Bar() {
+	_, _ = ioutil.ReadFile("bar")
+	_, _ = ioutil.ReadFile("baz")
+	_ = os.Args
+}
+`), 0644)
+	require.NoError(t, err)
+	send(t, clientOut, 0, "textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": barURI}})
+	diagnostics = receive(t, r)["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 2)
+	send(t, clientOut, 4, "textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": barURI},
+		"range":        diagnostics[0].(map[string]any)["range"],
+		"context":      map[string]any{"diagnostics": diagnostics[:1]},
+	})
+	actions = receive(t, r)["result"].([]any)
+	require.Len(t, actions, 2)
+	assert.Equal(t, []any{
+		map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(8), "character": float64(8)},
+				"end":   map[string]any{"line": float64(8), "character": float64(23)},
+			},
+			"newText": "os.ReadFile",
+		},
+	}, actions[0].(map[string]any)["edit"].(map[string]any)["changes"].(map[string]any)[barURI])
+
+	err = os.WriteFile(barPath, []byte(`package bar
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func Bar() {
+	_, _ = os.ReadFile("bar")
+	_, _ = ioutil.ReadFile("baz")
+	_ = os.Args
+}
+`), 0644)
+	require.NoError(t, err)
+	send(t, clientOut, 0, "textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": barURI}})
+	diagnostics = receive(t, r)["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+	send(t, clientOut, 5, "textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": barURI},
+		"range":        diagnostics[0].(map[string]any)["range"],
+		"context":      map[string]any{"diagnostics": diagnostics},
+	})
+	actions = receive(t, r)["result"].([]any)
+	require.Len(t, actions, 2)
+	assert.Equal(t, []any{
+		map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(3), "character": float64(0)},
+				"end":   map[string]any{"line": float64(4), "character": float64(0)},
+			},
+			"newText": "",
+		},
+		map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(9), "character": float64(8)},
+				"end":   map[string]any{"line": float64(9), "character": float64(23)},
+			},
+			"newText": "os.ReadFile",
+		},
+	}, actions[0].(map[string]any)["edit"].(map[string]any)["changes"].(map[string]any)[barURI])
+
+	// the inserted comment does not whitelist the reference until a reason is added
+	err = os.WriteFile(path.Join(projectDir, "foo/foo.go"), []byte(`package foo
+
+import (
+	"io/ioutil"
+)
+
+func Foo() {
+	// OK: 
+	_, _ = ioutil.ReadFile("foo")
+}
+`), 0644)
+	require.NoError(t, err)
+	send(t, clientOut, 0, "textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": fooURI}})
+	published = receive(t, r)
+	assert.Len(t, published["params"].(map[string]any)["diagnostics"], 1)
+
+	// fixing the finding and saving the file clears the diagnostics
+	err = os.WriteFile(path.Join(projectDir, "foo/foo.go"), []byte("package foo\n"), 0644)
+	require.NoError(t, err)
+	send(t, clientOut, 0, "textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": fooURI}})
+	published = receive(t, r)
+	assert.Equal(t, map[string]any{"uri": fooURI, "diagnostics": []any{}}, published["params"])
+
+	send(t, clientOut, 6, "shutdown", nil)
+	assert.Contains(t, receive(t, r), "result")
+	send(t, clientOut, 0, "exit", nil)
+	require.NoError(t, <-serveErr)
+}
+
+// send sends a request with the provided ID or, if the ID is 0, a notification.
+func send(t *testing.T, w io.Writer, id int, method string, params any) {
+	msg := map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	}
+	if id != 0 {
+		msg["id"] = id
+	}
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(t, err)
+}
+
+func receive(t *testing.T, r *bufio.Reader) map[string]any {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	require.NoError(t, err)
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	require.NoError(t, err)
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	require.NoError(t, err)
+	var msg map[string]any
+	require.NoError(t, json.Unmarshal(body, &msg))
+	return msg
+}
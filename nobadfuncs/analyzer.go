@@ -0,0 +1,114 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/token"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/analysis"
+)
+
+// NewAnalyzer returns an analyzer that reports the references in the analyzed packages that violate the provided
+// configuration. The category of each diagnostic is the ID of the rule that was violated. Relative include and
+// vulnerability database paths in the configuration are resolved against dir.
+//
+// An analyzer only has access to the syntax of the package being analyzed, so annotations in the source of other
+// packages are not supported. Returns an error if the configuration uses any other feature that requires the syntax or
+// module of other packages (see checkAnalyzerConfig), since such rules would otherwise never report anything. As on the
+// command line, the test files of packages are only checked against sequence rules.
+func NewAnalyzer(cfg Config, dir string) (*analysis.Analyzer, error) {
+	cfg, err := cfg.Resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAnalyzerConfig(cfg); err != nil {
+		return nil, err
+	}
+	c, err := newChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.Analyzer{
+		Name: "nobadfuncs",
+		Doc:  "reports references to functions and other objects that are not allowed",
+		URL:  "https://github.com/palantir/go-nobadfuncs",
+		Run: func(pass *analysis.Pass) (any, error) {
+			modulePath := ""
+			if pass.Module != nil {
+				modulePath = pass.Module.Path
+			}
+			pkg := packageInfo{
+				Path:       pass.Pkg.Path(),
+				ModulePath: modulePath,
+				Fset:       pass.Fset,
+				Files:      pass.Files,
+				Types:      pass.Pkg,
+				Info:       pass.TypesInfo,
+			}
+			// the package is analyzed with and without its test files, so a package with test files is a test variant
+			// whose test files are checked as on the command line
+			pkg.TestVariant = len(testFiles(pkg)) > 0
+			for _, finding := range c.checkPackage(pkg, false) {
+				msg := finding.Message
+				if finding.Via != "" {
+					msg += " (low confidence: referenced via " + finding.Via + ")"
+				}
+				pass.Report(analysis.Diagnostic{
+					Pos:      posOf(pass, finding.Pos),
+					Category: finding.RuleID,
+					Message:  msg,
+					URL:      finding.DocURL,
+				})
+			}
+			return nil, nil
+		},
+	}, nil
+}
+
+// checkAnalyzerConfig returns an error if the provided resolved configuration uses a feature that is not supported by
+// analyzers because the checker of an analyzer is not created using withLoadedPackages: deprecated objects (see
+// DeprecatedConfig) and the "defined-types" match option, which require the syntax of other packages, and the version
+// ranges of deny rules (including the rules generated from a vulnerability database), which require the module of the
+// referenced object. The other features that depend on the loaded packages cannot be rejected based on the
+// configuration and are not supported silently: annotations in the source of referenced objects are not enforced and
+// the "{{.Module}}" and "{{.Version}}" placeholders of message templates are empty.
+func checkAnalyzerConfig(cfg Config) error {
+	for i, rule := range cfg.Deny {
+		if rule.Versions != "" {
+			return errors.Errorf("deny rule %s specifies versions, which are not supported by the analyzer (rules generated from a vulnerability database always specify versions)", ruleName(rule.ID, i))
+		}
+	}
+	if cfg.Deprecated.Enabled {
+		return errors.Errorf("deprecated objects are not supported by the analyzer")
+	}
+	if cfg.Match.DefinedTypes {
+		return errors.Errorf("the defined-types match option is not supported by the analyzer")
+	}
+	return nil
+}
+
+// posOf returns the token.Pos of the provided position in the files of the provided pass. Returns token.NoPos if the
+// position is not in any of the files.
+func posOf(pass *analysis.Pass, pos token.Position) token.Pos {
+	for _, file := range pass.Files {
+		tokFile := pass.Fset.File(file.Pos())
+		if tokFile == nil || tokFile.Name() != pos.Filename || pos.Line > tokFile.LineCount() {
+			continue
+		}
+		return tokFile.LineStart(pos.Line) + token.Pos(pos.Column-1)
+	}
+	return token.NoPos
+}
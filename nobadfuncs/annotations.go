@@ -0,0 +1,130 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	// annotationRuleID is the ID of the findings for references that violate an annotation in the source of the
+	// referenced object.
+	annotationRuleID = "annotation"
+
+	denyDirective     = "//nobadfuncs:deny"
+	restrictDirective = "//nobadfuncs:restrict"
+)
+
+// annotation is the combination of the "//nobadfuncs:" directives in the documentation of an object.
+type annotation struct {
+	// deny is true if all references to the object are denied.
+	deny bool
+	// reason is the text that follows the deny directive.
+	reason string
+	// restrict are the patterns specified by the restrict directives. If non-empty, only references from the packages
+	// that match the patterns are allowed. Relative patterns are resolved against modulePath.
+	restrict    pkgPatterns
+	restrictRaw []string
+	// modulePath is the path of the module that contains the object.
+	modulePath string
+}
+
+// annotations records the annotations of the package-level objects and methods in the loaded packages and their
+// dependencies.
+type annotations map[types.Object]annotation
+
+func newAnnotations(pkgs []*packages.Package) annotations {
+	out := make(annotations)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.TypesInfo == nil {
+			return
+		}
+		modulePath := ""
+		if pkg.Module != nil {
+			modulePath = pkg.Module.Path
+		}
+		visitObjectDocs(pkg, func(obj types.Object, doc *ast.CommentGroup) {
+			if a, ok := parseAnnotation(doc); ok {
+				a.modulePath = modulePath
+				out[obj] = a
+			}
+		})
+	})
+	return out
+}
+
+// parseAnnotation returns the annotation specified by the directives in the provided documentation. Returns false if
+// the documentation does not contain any directives. Restrict directives with invalid patterns are ignored, since they
+// are in the source of dependencies that cannot be changed by the user of the checker.
+func parseAnnotation(doc *ast.CommentGroup) (annotation, bool) {
+	var out annotation
+	found := false
+	for _, comment := range doc.List {
+		if _, ok := directiveArgs(comment.Text, denyDirective); ok {
+			out.deny = true
+			out.reason = strings.TrimSpace(strings.TrimPrefix(comment.Text, denyDirective))
+			found = true
+		} else if args, ok := directiveArgs(comment.Text, restrictDirective); ok {
+			patterns, err := newPkgPatterns(args)
+			if err != nil || len(patterns) == 0 {
+				continue
+			}
+			out.restrict = append(out.restrict, patterns...)
+			out.restrictRaw = append(out.restrictRaw, args...)
+			found = true
+		}
+	}
+	return out, found
+}
+
+// directiveArgs returns the whitespace-separated arguments of the provided comment if it is the provided directive.
+// Returns false if it is not.
+func directiveArgs(comment, directive string) ([]string, bool) {
+	rest, ok := strings.CutPrefix(comment, directive)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+	return strings.Fields(rest), true
+}
+
+// checkAnnotation returns the finding for the provided reference if it violates the annotation of the referenced
+// object.
+func (c *checker) checkAnnotation(pkg packageInfo, ref objRef, data MessageData) (Finding, bool) {
+	obj := ref.Obj
+	if fn, ok := obj.(*types.Func); ok {
+		obj = fn.Origin()
+	}
+	a, ok := c.annotations[obj]
+	if !ok {
+		return Finding{}, false
+	}
+	if a.deny {
+		msg := defaultDenyMessage(ref.Sig)
+		if a.reason != "" {
+			msg = fmt.Sprintf("references to %q are not allowed: %s.", ref.Sig, strings.TrimSuffix(a.reason, "."))
+		}
+		return c.ruleFinding(ref.Sig, annotationRuleID, "", "", nil, msg, data), true
+	}
+	if len(a.restrict) > 0 && !a.restrict.matches(pkg.Path, a.modulePath) {
+		msg := fmt.Sprintf("references to %q are only allowed from %s.", ref.Sig, strings.Join(a.restrictRaw, ", "))
+		return c.ruleFinding(ref.Sig, annotationRuleID, "", "", nil, msg, data), true
+	}
+	return Finding{}, false
+}
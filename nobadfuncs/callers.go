@@ -0,0 +1,99 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+type callerMatcher struct {
+	CallerRule
+	refs refPatterns
+	pkgs pkgPatterns
+	msg  *template.Template
+}
+
+func newCallerMatcher(rule CallerRule) (callerMatcher, error) {
+	if len(rule.Packages) == 0 && len(rule.Files) == 0 {
+		return callerMatcher{}, errors.Errorf("at least one of packages and files must be specified")
+	}
+	refs, err := newRefPatterns(rule.Refs)
+	if err != nil {
+		return callerMatcher{}, err
+	}
+	pkgs, err := newPkgPatterns(rule.Packages)
+	if err != nil {
+		return callerMatcher{}, err
+	}
+	for _, pattern := range rule.Files {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return callerMatcher{}, errors.Wrapf(err, "invalid file pattern %q", pattern)
+		}
+	}
+	msg, err := newMessageTemplate(rule.Message)
+	if err != nil {
+		return callerMatcher{}, err
+	}
+	return callerMatcher{
+		CallerRule: rule,
+		refs:       refs,
+		pkgs:       pkgs,
+		msg:        msg,
+	}, nil
+}
+
+// allows returns true if the rule allows references from the provided package and file.
+func (m callerMatcher) allows(pkg packageInfo, filename string) bool {
+	if len(m.pkgs) > 0 && m.pkgs.matches(pkg.Path, pkg.ModulePath) {
+		return true
+	}
+	for _, pattern := range m.Files {
+		if ok, _ := path.Match(pattern, filepath.Base(filename)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns a description of the packages and files from which the rule allows references.
+func (m callerMatcher) allowed() string {
+	var parts []string
+	if len(m.Packages) > 0 {
+		parts = append(parts, "packages "+strings.Join(m.Packages, ", "))
+	}
+	if len(m.Files) > 0 {
+		parts = append(parts, "files "+strings.Join(m.Files, ", "))
+	}
+	return strings.Join(parts, " or ")
+}
+
+// checkCallers returns the finding for the provided reference, which is made from the provided file of the provided
+// package, if it violates a caller rule.
+func (c *checker) checkCallers(pkg packageInfo, ref objRef, filename string, data MessageData) (Finding, bool) {
+	for _, rule := range c.callers {
+		if !rule.refs.matches(ref) || rule.allows(pkg, filename) {
+			continue
+		}
+		msg := fmt.Sprintf("references to %q from package %s are only allowed from %s.", ref.Sig, removeVendor(pkg.Path), rule.allowed())
+		return c.ruleFinding(ref.Sig, rule.ID, rule.Replacement, rule.DocURL, rule.msg, msg, data), true
+	}
+	return Finding{}, false
+}
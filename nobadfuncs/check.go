@@ -0,0 +1,563 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// Finding is a reference that violates the configuration of a check.
+type Finding struct {
+	// Pos is the position of the reference.
+	Pos token.Position `json:"pos"`
+	// Ref is the string representation of the referenced object. For functions and methods, this is the FuncRef.
+	Ref string `json:"ref"`
+	// RuleID is the ID of the rule that was violated, if any.
+	RuleID string `json:"ruleId,omitempty"`
+	// Message describes the violation.
+	Message string `json:"message"`
+	// Replacement is the recommended replacement specified by the rule that was violated, if any.
+	Replacement string `json:"replacement,omitempty"`
+	// DocURL is the documentation URL specified by the rule that was violated, if any.
+	DocURL string `json:"docUrl,omitempty"`
+	// Via is non-empty if the object is not referenced directly, but by name through reflection, a plugin lookup or a
+	// "//go:linkname" directive. Such findings are of lower confidence because the referenced object is determined
+	// heuristically.
+	Via string `json:"via,omitempty"`
+	// Owners are the owners of the file that contains the reference as specified by Options.CodeOwners, if any.
+	Owners []string `json:"owners,omitempty"`
+	// Package is the import path of the package that contains the reference.
+	Package string `json:"package"`
+	// Suppression is the reason of the "// OK: [reason]" comment that whitelists the finding. Whitelisted findings are
+	// only returned if Options.IncludeSuppressed is true.
+	Suppression string `json:"suppression,omitempty"`
+}
+
+// String returns the representation of the finding used for text output.
+func (f Finding) String() string {
+	out := fmt.Sprintf("%s: %s", f.Pos.String(), f.Message)
+	if f.Via != "" {
+		out += fmt.Sprintf(" (low confidence: referenced via %s)", f.Via)
+	}
+	if f.Suppression != "" {
+		out += fmt.Sprintf(" (suppressed: %s)", f.Suppression)
+	}
+	return out
+}
+
+// Check returns the references in the provided packages that violate the provided configuration. Findings are returned
+// in the order in which the packages are loaded and are sorted by position within each package. Relative include paths
+// in the configuration are resolved against dir.
+func Check(pkgs []string, cfg Config, dir string, opts Options) ([]Finding, error) {
+	cfg, err := cfg.Resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// test files are only checked against sequence rules, so their packages are only loaded if there are any
+	tests := len(c.sequences) > 0
+	var hierarchy *configHierarchy
+	if opts.DiscoverConfig {
+		hierarchy = newConfigHierarchy(cfg, dir)
+		applies, sequences, err := hierarchy.scan(pkgs, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !applies && cfg.empty() {
+			// no rules apply to the packages, so they do not need to be loaded
+			return nil, nil
+		}
+		tests = tests || sequences
+	}
+	changed, err := newChangedLines(opts, dir)
+	if err != nil {
+		return nil, err
+	}
+	var owners *codeOwners
+	if opts.CodeOwners != "" {
+		loaded, err := loadCodeOwners(opts.CodeOwners)
+		if err != nil {
+			return nil, err
+		}
+		owners = &loaded
+	}
+	loadedPkgs, err := loadPackages(pkgs, dir, tests)
+	if err != nil {
+		return nil, err
+	}
+
+	c = c.withLoadedPackages(loadedPkgs)
+
+	checkers := make(map[string]*checker)
+
+	var findings []Finding
+	for _, loadedPkg := range loadedPkgs {
+		if isTestMain(loadedPkg) {
+			continue
+		}
+		pkgChecker := c
+		if hierarchy != nil {
+			effective, err := hierarchy.configFor(loadedPkg)
+			if err != nil {
+				return nil, err
+			}
+			if effective.key != "" {
+				if pkgChecker = checkers[effective.key]; pkgChecker == nil {
+					if pkgChecker, err = newChecker(effective.cfg); err != nil {
+						return nil, errors.Wrapf(err, "invalid configuration for package %s", loadedPkg.PkgPath)
+					}
+					pkgChecker = pkgChecker.withLoadedPackages(loadedPkgs)
+					checkers[effective.key] = pkgChecker
+				}
+			}
+		}
+		for _, finding := range pkgChecker.checkPackage(newPackageInfo(loadedPkg), opts.IncludeSuppressed) {
+			if changed != nil && !changed.contains(finding.Pos) {
+				continue
+			}
+			if owners != nil {
+				finding.Owners = owners.owners(finding.Pos.Filename)
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// packageInfo is the information about a package that is required to check it.
+type packageInfo struct {
+	Path       string
+	ModulePath string
+	Fset       *token.FileSet
+	Files      []*ast.File
+	Types      *types.Package
+	Info       *types.Info
+	// TestVariant is true if the package is the variant of a package that includes its test files or an external test
+	// package. Only the test files of such packages are checked, and only against sequence rules.
+	TestVariant bool
+}
+
+func newPackageInfo(pkg *packages.Package) packageInfo {
+	info := packageInfo{
+		Path:        pkg.PkgPath,
+		Fset:        pkg.Fset,
+		Files:       pkg.Syntax,
+		Types:       pkg.Types,
+		Info:        pkg.TypesInfo,
+		TestVariant: isTestVariant(pkg),
+	}
+	if pkg.Module != nil {
+		info.ModulePath = pkg.Module.Path
+	}
+	return info
+}
+
+// checker checks packages against a configuration whose patterns have been compiled.
+type checker struct {
+	match      MatchConfig
+	suffix     *template.Template
+	funcs      map[string]string
+	deny       []denyMatcher
+	layers     layerMatchers
+	allow      []allowMatcher
+	decls      []declarationMatcher
+	constructs []constructMatcher
+	fields     []fieldMatcher
+	callers    []callerMatcher
+	sequences  []sequenceMatcher
+
+	// contexts are the contexts used by any deny rule.
+	contexts syntaxContext
+	// discarded is true if any deny rule only applies to calls whose results are discarded.
+	discarded bool
+	// enclosingParams is true if any deny rule depends on the parameters of the enclosing functions of a reference.
+	enclosingParams bool
+
+	// deprecated is the configuration for references to deprecated objects. Nil if disabled.
+	deprecated *deprecatedMatcher
+	// deprecations are the deprecated objects and packages. Only populated if deprecated is non-nil.
+	deprecations deprecations
+	// annotations are the annotations of the objects in the loaded packages.
+	annotations annotations
+	// modules maps each loaded package to the module that contains it.
+	modules map[*types.Package]*packages.Module
+	// definedFrom maps each type defined from another named type to the type it is defined from. Only populated if
+	// match.DefinedTypes is true.
+	definedFrom map[*types.TypeName]*types.TypeName
+}
+
+type denyMatcher struct {
+	Rule
+	refs     refPatterns
+	pkgs     pkgPatterns
+	versions versionRange
+	contexts syntaxContext
+	msg      *template.Template
+}
+
+type allowMatcher struct {
+	AllowRule
+	from refPatterns
+	refs refPatterns
+	pkgs pkgPatterns
+	msg  *template.Template
+}
+
+func newChecker(cfg Config) (*checker, error) {
+	suffix, err := newMessageTemplate(cfg.MessageSuffix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid message suffix")
+	}
+	c := &checker{
+		match:  cfg.Match,
+		suffix: suffix,
+		funcs:  cfg.Funcs,
+	}
+	for i, rule := range cfg.Deny {
+		refs, err := newRefPatterns(rule.Refs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid deny rule %s", ruleName(rule.ID, i))
+		}
+		pkgs, err := newPkgPatterns(rule.Packages)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid deny rule %s", ruleName(rule.ID, i))
+		}
+		versions, err := newVersionRange(rule.Versions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid deny rule %s", ruleName(rule.ID, i))
+		}
+		contexts, err := parseSyntaxContext(rule.Context)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid deny rule %s", ruleName(rule.ID, i))
+		}
+		if rule.When != "" && rule.When != whenResultDiscarded {
+			return nil, errors.Errorf("invalid deny rule %s: invalid when %q: must be %q", ruleName(rule.ID, i), rule.When, whenResultDiscarded)
+		}
+		msg, err := newMessageTemplate(rule.Message)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid deny rule %s", ruleName(rule.ID, i))
+		}
+		c.deny = append(c.deny, denyMatcher{
+			Rule:     rule,
+			refs:     refs,
+			pkgs:     pkgs,
+			versions: versions,
+			contexts: contexts,
+			msg:      msg,
+		})
+		c.contexts |= contexts
+		c.enclosingParams = c.enclosingParams || rule.EnclosingParam != ""
+		c.discarded = c.discarded || rule.When != ""
+	}
+	layers, err := newLayerMatchers(cfg.Layers)
+	if err != nil {
+		return nil, err
+	}
+	c.layers = layers
+	if c.deprecated, err = newDeprecatedMatcher(cfg.Deprecated); err != nil {
+		return nil, err
+	}
+	for i, rule := range cfg.Allow {
+		from, err := newRefPatterns(rule.From)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid allow rule %s", ruleName(rule.ID, i))
+		}
+		refs, err := newRefPatterns(rule.Refs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid allow rule %s", ruleName(rule.ID, i))
+		}
+		pkgs, err := newPkgPatterns(rule.Packages)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid allow rule %s", ruleName(rule.ID, i))
+		}
+		msg, err := newMessageTemplate(rule.Message)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid allow rule %s", ruleName(rule.ID, i))
+		}
+		c.allow = append(c.allow, allowMatcher{
+			AllowRule: rule,
+			from:      from,
+			refs:      refs,
+			pkgs:      pkgs,
+			msg:       msg,
+		})
+	}
+	for i, rule := range cfg.Callers {
+		caller, err := newCallerMatcher(rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid caller rule %s", ruleName(rule.ID, i))
+		}
+		c.callers = append(c.callers, caller)
+	}
+	for i, rule := range cfg.Sequences {
+		sequence, err := newSequenceMatcher(rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sequence rule %s", ruleName(rule.ID, i))
+		}
+		c.sequences = append(c.sequences, sequence)
+	}
+	for i, rule := range cfg.Declarations {
+		decl, err := newDeclarationMatcher(rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid declaration rule %s", ruleName(rule.ID, i))
+		}
+		c.decls = append(c.decls, decl)
+	}
+	for i, rule := range cfg.Constructs {
+		construct, err := newConstructMatcher(rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid construct rule %s", ruleName(rule.ID, i))
+		}
+		c.constructs = append(c.constructs, construct)
+	}
+	for i, rule := range cfg.Fields {
+		field, err := newFieldMatcher(rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid field rule %s", ruleName(rule.ID, i))
+		}
+		c.fields = append(c.fields, field)
+	}
+	return c, nil
+}
+
+// withLoadedPackages returns a copy of the checker that has the information from the provided packages and their
+// dependencies that is required by its configuration.
+func (c *checker) withLoadedPackages(pkgs []*packages.Package) *checker {
+	out := *c
+	out.modules = packageModules(pkgs)
+	out.annotations = newAnnotations(pkgs)
+	if c.match.DefinedTypes {
+		out.definedFrom = definedFromTypes(pkgs)
+	}
+	if c.deprecated != nil {
+		out.deprecations = newDeprecations(pkgs)
+	}
+	return &out
+}
+
+// ruleName returns the name used to identify a rule in error messages.
+func ruleName(id string, idx int) string {
+	if id != "" {
+		return fmt.Sprintf("%q", id)
+	}
+	return fmt.Sprintf("at index %d", idx)
+}
+
+// checkPackage returns the findings for the provided package sorted by position, including the findings for indirect
+// references and declarations. Findings that are whitelisted using an "OK" comment are omitted unless includeSuppressed
+// is true, in which case their Suppression is set to the reason of the comment. Only the test files of test variants
+// are checked, and only against sequence rules.
+func (c *checker) checkPackage(pkg packageInfo, includeSuppressed bool) []Finding {
+	if pkg.TestVariant {
+		pkg.Files = testFiles(pkg)
+	}
+	comments := fileLineCommentMap(pkg.Fset, pkg.Files)
+	whitelistComments := comments
+	if includeSuppressed {
+		// findings are checked against the comments once all of them have been collected
+		whitelistComments = nil
+	}
+	funcs := newEnclosingFuncs(pkg)
+
+	var findings []Finding
+	if pkg.TestVariant {
+		findings = c.checkSequences(pkg, whitelistComments, funcs)
+	} else {
+		sites := refSites{funcs: funcs}
+		if c.contexts != 0 {
+			sites.contexts = newSyntaxContexts(pkg)
+		}
+		if c.enclosingParams {
+			sites.params = newEnclosingParams(pkg)
+		}
+		if c.discarded {
+			sites.discarded = newDiscardedCalls(pkg)
+		}
+		findings = c.checkRefs(pkg, whitelistComments, sites)
+		findings = append(findings, c.checkIndirectRefs(pkg, whitelistComments, sites)...)
+		findings = append(findings, c.checkSequences(pkg, whitelistComments, funcs)...)
+		findings = append(findings, c.checkDeclarations(pkg, whitelistComments)...)
+		findings = append(findings, c.checkConstructs(pkg, whitelistComments, funcs)...)
+		findings = append(findings, c.checkFields(pkg, whitelistComments, funcs)...)
+	}
+	for i := range findings {
+		findings[i].Package = removeVendor(pkg.Path)
+		if includeSuppressed {
+			findings[i].Suppression = suppression(comments, findings[i].Pos)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return posLess(findings[i].Pos, findings[j].Pos)
+	})
+	return findings
+}
+
+// checkRefs returns the findings for the objects that are referenced directly in the provided package in order of
+// position.
+func (c *checker) checkRefs(pkg packageInfo, comments map[string]map[int]string, sites refSites) []Finding {
+	selections := identSelections(pkg)
+	var keys []*ast.Ident
+	for k := range pkg.Info.Uses {
+		keys = append(keys, k)
+	}
+	sort.Sort(identSlice(keys))
+
+	var findings []Finding
+	for _, id := range keys {
+		ref, ok := newObjRef(pkg.Info.Uses[id])
+		if !ok {
+			continue
+		}
+		pos := pkg.Fset.Position(id.Pos())
+		if isWhitelisted(comments, pos) {
+			continue
+		}
+		ref.Alts = append(ref.Alts, c.equivalentRefs(ref, selections[id])...)
+		if finding, ok := c.checkRef(pkg, ref, sites, id.Pos()); ok {
+			finding.Pos = pos
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// checkRef returns the finding for the provided reference, which is made at the provided position. Returns false if the
+// reference does not violate any rule. If a reference violates multiple rules, the finding for the first rule is
+// returned: function signatures are checked first, followed by deny rules, caller rules, annotations of the referenced
+// object, deprecated objects, layers and then allow rules, in the order in which they are configured.
+func (c *checker) checkRef(pkg packageInfo, ref objRef, sites refSites, pos token.Pos) (Finding, bool) {
+	data := c.refMessageData(pkg, ref, sites, pos)
+	if finding, ok := c.checkDenied(pkg, ref, sites, pos, data); ok {
+		return finding, true
+	}
+	if ref.PkgPath == removeVendor(pkg.Path) {
+		// references to objects in the package being checked are always allowed
+		return Finding{}, false
+	}
+	if finding, ok := c.checkCallers(pkg, ref, pkg.Fset.Position(pos).Filename, data); ok {
+		return finding, true
+	}
+	if finding, ok := c.checkAnnotation(pkg, ref, data); ok {
+		return finding, true
+	}
+	if finding, ok := c.checkDeprecated(pkg, ref, data); ok {
+		return finding, true
+	}
+	if msg, ok := c.layers.check(pkg, ref); ok {
+		return Finding{
+			Ref:     ref.Sig,
+			Message: c.render(nil, msg, whitelistHint, data),
+		}, true
+	}
+	for _, rule := range c.allow {
+		if !rule.pkgs.matches(pkg.Path, pkg.ModulePath) {
+			continue
+		}
+		if len(rule.from) > 0 && !rule.from.matches(ref) {
+			continue
+		}
+		if rule.refs.matches(ref) {
+			continue
+		}
+		return c.ruleFinding(ref.Sig, rule.ID, rule.Replacement, rule.DocURL, rule.msg, fmt.Sprintf("references to %q are not in the allow-list.", ref.Sig), data), true
+	}
+	return Finding{}, false
+}
+
+// refMessageData returns the message data for the provided reference, which is made at the provided position.
+func (c *checker) refMessageData(pkg packageInfo, ref objRef, sites refSites, pos token.Pos) MessageData {
+	data := MessageData{
+		Signature: ref.Sig,
+		Caller:    sites.funcs.name(pos),
+		Package:   removeVendor(pkg.Path),
+	}
+	data.Module, data.Version = moduleVersion(c.modules[ref.Obj.Pkg()])
+	return data
+}
+
+// checkDenied returns the finding for the provided reference, which is made at the provided position, if it matches a
+// function signature or a deny rule. Returns false otherwise.
+func (c *checker) checkDenied(pkg packageInfo, ref objRef, sites refSites, pos token.Pos, data MessageData) (Finding, bool) {
+	for _, sig := range ref.sigs() {
+		reason, ok := c.funcs[sig]
+		if !ok {
+			continue
+		}
+		return Finding{
+			Ref:     ref.Sig,
+			Message: c.funcMessage(reason, data),
+		}, true
+	}
+	for _, rule := range c.deny {
+		if !rule.pkgs.matches(pkg.Path, pkg.ModulePath) || !rule.refs.matches(ref) {
+			continue
+		}
+		if rule.versions != nil && !rule.versions.contains(data.Version) {
+			continue
+		}
+		if rule.EnclosingParam != "" && !sites.params.has(pos, rule.EnclosingParam) {
+			continue
+		}
+		if rule.When == whenResultDiscarded && !sites.discarded[pos] {
+			continue
+		}
+		defaultMsg := defaultDenyMessage(ref.Sig)
+		if rule.When == whenResultDiscarded {
+			defaultMsg = fmt.Sprintf("the results of %q must not be discarded.", ref.Sig)
+		}
+		if rule.EnclosingParam != "" {
+			defaultMsg = fmt.Sprintf("references to %q are not allowed in functions that have a parameter of type %s.", ref.Sig, rule.EnclosingParam)
+		}
+		if rule.contexts != 0 {
+			ctx := rule.contexts & sites.contexts[pos]
+			if ctx == 0 {
+				continue
+			}
+			defaultMsg = fmt.Sprintf("references to %q are not allowed %s.", ref.Sig, ctx.description())
+		}
+		return c.ruleFinding(ref.Sig, rule.ID, rule.Replacement, rule.DocURL, rule.msg, defaultMsg, data), true
+	}
+	return Finding{}, false
+}
+
+// ruleFinding returns the finding for a violation of the rule with the provided ID, replacement, documentation URL and
+// message template.
+func (c *checker) ruleFinding(ref, id, replacement, docURL string, msg *template.Template, defaultMsg string, data MessageData) Finding {
+	data.ID = id
+	data.Replacement = replacement
+	data.DocURL = docURL
+	return Finding{
+		Ref:         ref,
+		RuleID:      id,
+		Message:     c.render(msg, defaultMsg, whitelistHint, data),
+		Replacement: replacement,
+		DocURL:      docURL,
+	}
+}
+
+func defaultDenyMessage(sig string) string {
+	return fmt.Sprintf("references to %q are not allowed.", sig)
+}
@@ -0,0 +1,378 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration for a check.
+type Config struct {
+	// Presets are the names of the built-in presets that are merged into the configuration (see Resolve).
+	Presets []string `json:"presets,omitempty" yaml:"presets,omitempty"`
+	// Include are the paths of the configuration files that are merged into the configuration (see Resolve). Relative
+	// paths are resolved against the directory of the including file.
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	// Overrides modify or disable the rules with the specified IDs after presets and included files are merged.
+	Overrides []RuleOverride `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+	// Funcs maps the signatures of the functions that may not be referenced to the reason they are not allowed. This is
+	// the format of the "--config-json" flag. The reason is used as the message verbatim (it is not a template). If the
+	// reason is empty, a default message is used.
+	Funcs map[string]string `json:"funcs,omitempty" yaml:"funcs,omitempty"`
+	// Deny are the rules that specify references that are not allowed.
+	Deny []Rule `json:"deny,omitempty" yaml:"deny,omitempty"`
+	// Allow are the rules that specify the only references that are allowed.
+	Allow []AllowRule `json:"allow,omitempty" yaml:"allow,omitempty"`
+	// Layers are the architectural layers of the packages being checked. References from a package in one layer to an
+	// object in a package in another layer are only allowed if the referencing layer allows the referenced layer.
+	Layers []Layer `json:"layers,omitempty" yaml:"layers,omitempty"`
+	// Callers are the rules that restrict the packages and files from which objects may be referenced.
+	Callers []CallerRule `json:"callers,omitempty" yaml:"callers,omitempty"`
+	// Sequences are the rules that require or forbid calls relative to other calls in the same function.
+	Sequences []SequenceRule `json:"sequences,omitempty" yaml:"sequences,omitempty"`
+	// Declarations are the rules that specify function, method and type declarations that are not allowed.
+	Declarations []DeclarationRule `json:"declarations,omitempty" yaml:"declarations,omitempty"`
+	// Constructs are the rules that specify language constructs that are not allowed.
+	Constructs []ConstructRule `json:"constructs,omitempty" yaml:"constructs,omitempty"`
+	// Fields are the rules that specify constant values that may not be assigned to struct fields.
+	Fields []FieldRule `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// VulnDB is the path of a directory that contains an OSV-format vulnerability database (such as a copy of the
+	// database used by govulncheck). References to the vulnerable symbols listed in its entries are reported if the
+	// version of their module in the build list is affected. Each entry is added as a deny rule whose ID is the ID of the
+	// entry (see Resolve). A relative path is resolved against the directory of the configuration file.
+	VulnDB string `json:"vulndb,omitempty" yaml:"vulndb,omitempty"`
+	// Deprecated configures the reporting of references to objects whose documentation has a "Deprecated:" paragraph.
+	Deprecated DeprecatedConfig `json:"deprecated,omitzero" yaml:"deprecated,omitempty"`
+	// MessageSuffix is the template for the suffix that is appended to default messages and that is available to message
+	// templates as "{{.Suffix}}". If empty, the suffix describes how to whitelist a finding using an "OK" comment.
+	MessageSuffix string `json:"message-suffix,omitempty" yaml:"message-suffix,omitempty"`
+	// Match configures the equivalent forms of a reference that are also matched against function signatures and deny
+	// rules.
+	Match MatchConfig `json:"match,omitzero" yaml:"match,omitempty"`
+
+	// path is the absolute path of the file from which the configuration was loaded by LoadConfig, if any. A discovered
+	// configuration file with the same path is not applied on top of the configuration again.
+	path string
+}
+
+// MatchConfig configures the equivalent forms of a reference that are matched against function signatures and deny
+// rules in addition to the referenced object itself. Regardless of this configuration, a reference to a promoted method
+// always matches the method of the embedded type (a call to "wrapper.Do" where "wrapper" embeds "*net/http.Client"
+// matches "(*net/http.Client).Do") because that is the object that is referenced.
+type MatchConfig struct {
+	// PromotedMethods specifies that a reference to a method promoted through an embedded field also matches the method
+	// of the embedding type. For example, if "Wrapper" embeds "*net/http.Client", then "wrapper.Do" also matches
+	// "(*example.com/foo.Wrapper).Do" and "(example.com/foo.Wrapper).Do".
+	PromotedMethods bool `json:"promoted-methods,omitempty" yaml:"promoted-methods,omitempty"`
+	// DefinedTypes specifies that a reference to a type defined from another named type (as in
+	// "type MyClient net/http.Client") also matches the type it is defined from and that a reference to a method of such
+	// a type also matches the method with the same name of the type it is defined from.
+	DefinedTypes bool `json:"defined-types,omitempty" yaml:"defined-types,omitempty"`
+	// Aliases specifies that a reference to an alias (as in "type MyClient = net/http.Client") also matches the type it
+	// denotes and that a reference to a function whose signature contains aliases also matches the signature in which
+	// the aliases are replaced with the types they denote.
+	Aliases bool `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+// DeprecatedConfig configures the reporting of references to deprecated objects. An object is deprecated if its
+// documentation or the documentation of its package has a paragraph that begins with "Deprecated:". Because the syntax
+// of all dependencies is loaded, this applies to the standard library, third-party modules and the packages of the
+// module itself (although references to deprecated objects within their own package are not reported). Findings have
+// the rule ID "deprecated" and the text of the "Deprecated:" paragraph is available to message templates as
+// "{{.Replacement}}".
+type DeprecatedConfig struct {
+	// Enabled enables the reporting of references to deprecated objects.
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Refs are the patterns for the deprecated objects that are reported. If empty, all deprecated objects are reported.
+	Refs []string `json:"refs,omitempty" yaml:"refs,omitempty"`
+	// Packages are the patterns for the packages in which references to deprecated objects are reported. If empty,
+	// references are reported in all checked packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Message is the template for the message printed for references to deprecated objects (see MessageData). If empty,
+	// a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Rule is a rule that denies references to the objects that match any of its patterns.
+type Rule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Refs are the patterns for the references that are not allowed. See the documentation of the "Config" section of
+	// the README for the pattern syntax.
+	Refs []string `json:"refs" yaml:"refs"`
+	// Packages are the patterns for the packages to which the rule applies. If empty, the rule applies to all checked
+	// packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Versions, if non-empty, is the range of versions of the module that contains the referenced object for which the
+	// rule applies, as in "< v1.4.2" or ">= v1.2.0, < v1.4.2 || >= v2.0.0, < v2.0.3". The version is the version of the
+	// module in the build list. References to objects that are not in a versioned module (such as objects in the
+	// standard library or in the main module) never match a rule with a version range.
+	Versions string `json:"versions,omitempty" yaml:"versions,omitempty"`
+	// Context, if non-empty, restricts the rule to references in any of the provided syntactic contexts: "loop" (the
+	// condition, post statement or body of a "for" statement or the body of a "range" statement), "init" (the body of
+	// an "init" function or the value of a package-level variable), "goroutine" (the body of a function literal started
+	// with a "go" statement) and "locked" (between calls to the Lock and Unlock methods of a mutex in the same block).
+	// A function literal starts a new context, except that function literals in a goroutine are also in the goroutine.
+	Context []string `json:"context,omitempty" yaml:"context,omitempty"`
+	// EnclosingParam, if non-empty, restricts the rule to references in functions that have a parameter of the provided
+	// type or in function literals for which the function literal or any of the functions that contain it has such a
+	// parameter. The type is written with fully qualified package paths, as in "context.Context" or
+	// "*net/http.Request".
+	EnclosingParam string `json:"enclosing-param,omitempty" yaml:"enclosing-param,omitempty"`
+	// When, if non-empty, restricts the rule to references that are used in a particular manner. The only supported
+	// value is "result-discarded", which restricts the rule to calls whose results are not used: calls in expression,
+	// "defer" and "go" statements and calls whose results are all assigned to the blank identifier.
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+	// Message is the template for the message printed for references that violate the rule (see MessageData). If
+	// empty, a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the references that violate the rule. Available to message
+	// templates as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// CallerRule is a rule that denies references to the objects that match any of its patterns from packages and files
+// that do not match any of its caller patterns. At least one of Packages and Files must be specified. References to
+// objects in the package being checked are always allowed.
+type CallerRule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Refs are the patterns for the references that are restricted by the rule.
+	Refs []string `json:"refs" yaml:"refs"`
+	// Packages are the patterns for the packages from which the references are allowed.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Files are the patterns for the names of the files from which the references are allowed, as in "*_test.go". The
+	// patterns use the syntax of path.Match and are matched against the base name of the file.
+	Files []string `json:"files,omitempty" yaml:"files,omitempty"`
+	// Message is the template for the message printed for references that violate the rule (see MessageData). If
+	// empty, a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the references that violate the rule. Available to message
+	// templates as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// SequenceRule is a rule for the calls that are made in the same function as a call that matches any of its patterns.
+// Exactly one of MustCall and NotAfter must be specified.
+type SequenceRule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Calls are the patterns for the calls to which the rule applies.
+	Calls []string `json:"calls" yaml:"calls"`
+	// Result is the index of the result of the call on which MustCall must be called.
+	Result int `json:"result,omitempty" yaml:"result,omitempty"`
+	// MustCall is the method that must be called (or deferred) on the result of the call, written as the selectors
+	// that are applied to the result (as in "Close" or "Body.Close"), or "()" if the result is a function that must
+	// be called. The call may be made in the function that makes the call or in any function literal that it contains.
+	// The rule does not apply if the result is used in a manner that makes other code responsible for the call, such
+	// as by being returned, passed as an argument or stored outside of the function.
+	MustCall string `json:"must-call,omitempty" yaml:"must-call,omitempty"`
+	// NotAfter are the patterns for the calls that may not precede the call in the same function.
+	NotAfter []string `json:"not-after,omitempty" yaml:"not-after,omitempty"`
+	// Packages are the patterns for the packages to which the rule applies. If empty, the rule applies to all checked
+	// packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Message is the template for the message printed for calls that violate the rule (see MessageData). If empty, a
+	// default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the calls that violate the rule. Available to message templates
+	// as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// AllowRule is a rule that denies all references that do not match any of its patterns. References to objects in the
+// package being checked and to builtins are always allowed.
+type AllowRule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Packages are the patterns for the packages to which the rule applies. If empty, the rule applies to all checked
+	// packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// From are the patterns for the references that are restricted by the rule. If empty, all references are restricted.
+	// For example, a rule with From "os" and Refs "os.Getenv" allows "os.Getenv" to be referenced but no other object in
+	// the "os" package, while references to other packages are not affected by the rule.
+	From []string `json:"from,omitempty" yaml:"from,omitempty"`
+	// Refs are the patterns for the references that are allowed.
+	Refs []string `json:"refs" yaml:"refs"`
+	// Message is the template for the message printed for references that violate the rule (see MessageData). If
+	// empty, a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the references that violate the rule. Available to message
+	// templates as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// DeclarationRule is a rule that denies declarations in the checked packages. If Implements is non-empty, the rule
+// denies the declaration of types that implement the interface. Otherwise, the rule denies the declaration of functions
+// and methods that match its Name, Signature and Receiver.
+type DeclarationRule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Name is the pattern for the names of the functions or methods that may not be declared, such as "MarshalJSON" or
+	// "Must*". "*" matches any string. If empty, all names match.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Signature, if non-empty, is the signature that the declared function or method must have to match the rule. The
+	// signature does not include the receiver or parameter names and uses full package paths, as in "func() string" or
+	// "func(context.Context, []byte) error".
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
+	// Receiver restricts the kind of declarations that match the rule: "function" only matches functions, "method"
+	// matches all methods, "value" matches methods with a value receiver and "pointer" matches methods with a pointer
+	// receiver. If empty, both functions and methods match.
+	Receiver string `json:"receiver,omitempty" yaml:"receiver,omitempty"`
+	// Implements, if non-empty, is the qualified name of an interface, such as "context.Context". Types declared in the
+	// checked packages whose value or pointer type implements the interface match the rule.
+	Implements string `json:"implements,omitempty" yaml:"implements,omitempty"`
+	// Packages are the patterns for the packages to which the rule applies. If empty, the rule applies to all checked
+	// packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Message is the template for the message printed for declarations that violate the rule (see MessageData). If
+	// empty, a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the declarations that violate the rule. Available to message
+	// templates as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// ConstructRule is a rule that denies a language construct in the checked packages.
+type ConstructRule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Construct is the construct that is not allowed. The only supported construct is "map-range", a "range" statement
+	// over a map (whose iteration order is not deterministic).
+	Construct string `json:"construct" yaml:"construct"`
+	// Packages are the patterns for the packages to which the rule applies. If empty, the rule applies to all checked
+	// packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Message is the template for the message printed for constructs that violate the rule (see MessageData). If empty,
+	// a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the constructs that violate the rule. Available to message
+	// templates as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// FieldRule is a rule that denies assigning a constant value to a struct field, either as a keyed element of a composite
+// literal (as in "tls.Config{InsecureSkipVerify: true}") or using an assignment statement. Exactly one of Equals and
+// LessThan must be specified.
+type FieldRule struct {
+	// ID identifies the rule.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Field is the qualified name of the field, as in "crypto/tls.Config.InsecureSkipVerify".
+	Field string `json:"field" yaml:"field"`
+	// Equals, if non-empty, is the value that may not be assigned to the field. The value is a boolean ("true" or
+	// "false"), a number or a string.
+	Equals string `json:"equals,omitempty" yaml:"equals,omitempty"`
+	// LessThan, if non-empty, is the number that the values assigned to the field may not be less than.
+	LessThan string `json:"less-than,omitempty" yaml:"less-than,omitempty"`
+	// Packages are the patterns for the packages to which the rule applies. If empty, the rule applies to all checked
+	// packages.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Message is the template for the message printed for assignments that violate the rule (see MessageData). If empty,
+	// a default message is used.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Replacement is the recommended replacement for the assignments that violate the rule. Available to message
+	// templates as "{{.Replacement}}".
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// DocURL is the URL of the documentation for the rule. Available to message templates as "{{.DocURL}}".
+	DocURL string `json:"doc-url,omitempty" yaml:"doc-url,omitempty"`
+}
+
+// RuleOverride modifies the deny, allow, caller, sequence, declaration, construct or field rules with its ID. Overrides are
+// typically used to disable or re-scope rules inherited from presets or included files.
+type RuleOverride struct {
+	// ID is the ID of the rules that are overridden.
+	ID string `json:"id" yaml:"id"`
+	// Disabled removes the rules.
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// Packages, if non-nil, replaces the package patterns of the rules. For caller rules, these are the packages from
+	// which references are allowed.
+	Packages []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+	// Message, if non-empty, replaces the message templates of the rules.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Layer is a named set of packages. A package belongs to the first layer whose patterns match it.
+type Layer struct {
+	// Name is the name of the layer.
+	Name string `json:"name" yaml:"name"`
+	// Packages are the patterns for the packages in the layer.
+	Packages []string `json:"packages" yaml:"packages"`
+	// Allow are the names of the other layers that packages in this layer may reference.
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	// Restricted specifies that packages that do not belong to any layer may not reference this layer. If false, the
+	// layer may be referenced by any package that is not in a layer.
+	Restricted bool `json:"restricted,omitempty" yaml:"restricted,omitempty"`
+}
+
+func (c Config) empty() bool {
+	return len(c.Funcs) == 0 && len(c.Deny) == 0 && len(c.Allow) == 0 && len(c.Layers) == 0 && len(c.Callers) == 0 &&
+		len(c.Sequences) == 0 && len(c.Declarations) == 0 && len(c.Constructs) == 0 && len(c.Fields) == 0 && c.VulnDB == "" &&
+		!c.Deprecated.Enabled && len(c.Presets) == 0 && len(c.Include) == 0
+}
+
+// LoadConfig reads the configuration in the specified YAML file and resolves its presets, includes and overrides.
+// Because YAML is a superset of JSON, the file may also be JSON. Returns an error if the file contains unknown keys.
+func LoadConfig(path string) (Config, error) {
+	cfg, err := readConfig(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.path, err = filepath.Abs(path); err != nil {
+		return Config{}, errors.Wrapf(err, "failed to determine absolute path of %s", path)
+	}
+	return cfg.Resolve(filepath.Dir(path))
+}
+
+// readConfig reads the configuration in the specified file without resolving it.
+func readConfig(path string) (Config, error) {
+	cfgBytes, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to read configuration file")
+	}
+	cfg, err := ParseConfig(cfgBytes)
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to parse configuration file %s", path)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses the provided YAML (or JSON) configuration.
+func ParseConfig(in []byte) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(in))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		return Config{}, errors.WithStack(err)
+	}
+	return cfg, nil
+}
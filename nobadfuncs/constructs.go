@@ -0,0 +1,102 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// constructMapRange is a "range" statement over a map, whose iteration order is not deterministic.
+	constructMapRange = "map-range"
+)
+
+type constructMatcher struct {
+	ConstructRule
+	pkgs pkgPatterns
+	msg  *template.Template
+}
+
+func newConstructMatcher(rule ConstructRule) (constructMatcher, error) {
+	switch rule.Construct {
+	case constructMapRange:
+	default:
+		return constructMatcher{}, errors.Errorf("invalid construct %q: must be %q", rule.Construct, constructMapRange)
+	}
+	pkgs, err := newPkgPatterns(rule.Packages)
+	if err != nil {
+		return constructMatcher{}, err
+	}
+	msg, err := newMessageTemplate(rule.Message)
+	if err != nil {
+		return constructMatcher{}, err
+	}
+	return constructMatcher{
+		ConstructRule: rule,
+		pkgs:          pkgs,
+		msg:           msg,
+	}, nil
+}
+
+// checkConstructs returns the findings for the language constructs in the provided package that violate construct
+// rules.
+func (c *checker) checkConstructs(pkg packageInfo, comments map[string]map[int]string, funcs enclosingFuncs) []Finding {
+	var mapRangeRules []constructMatcher
+	for _, rule := range c.constructs {
+		if rule.Construct == constructMapRange && rule.pkgs.matches(pkg.Path, pkg.ModulePath) {
+			mapRangeRules = append(mapRangeRules, rule)
+		}
+	}
+	if len(mapRangeRules) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			rangeStmt, ok := n.(*ast.RangeStmt)
+			if !ok {
+				return true
+			}
+			typ := pkg.Info.TypeOf(rangeStmt.X)
+			if typ == nil {
+				return true
+			}
+			if _, ok := typ.Underlying().(*types.Map); !ok {
+				return true
+			}
+			pos := pkg.Fset.Position(rangeStmt.Pos())
+			if isWhitelisted(comments, pos) {
+				return true
+			}
+			rule := mapRangeRules[0]
+			typeStr := types.TypeString(toTypeRemoveVendor(typ), nil)
+			finding := c.ruleFinding(typeStr, rule.ID, rule.Replacement, rule.DocURL, rule.msg, fmt.Sprintf("range over a map of type %q has a nondeterministic iteration order.", typeStr), MessageData{
+				Signature: typeStr,
+				Caller:    funcs.name(rangeStmt.Pos()),
+				Package:   removeVendor(pkg.Path),
+			})
+			finding.Pos = pos
+			findings = append(findings, finding)
+			return true
+		})
+	}
+	return findings
+}
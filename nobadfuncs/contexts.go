@@ -0,0 +1,257 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// syntaxContext is a set of the syntactic contexts in which a reference is made.
+type syntaxContext uint8
+
+const (
+	// contextLoop is the condition, post statement or body of a "for" statement or the body of a "range" statement.
+	contextLoop syntaxContext = 1 << iota
+	// contextInit is the body of an "init" function or the value of a package-level variable.
+	contextInit
+	// contextGoroutine is the body of a function literal that is started as a goroutine using a "go" statement.
+	contextGoroutine
+	// contextLocked is the part of a block between a call to the Lock or RLock method of a sync.Mutex or sync.RWMutex
+	// and the matching call to Unlock or RUnlock. A deferred unlock keeps the mutex locked until the end of the block.
+	contextLocked
+)
+
+// syntaxContexts are the names of the contexts in the order in which they are described.
+var syntaxContexts = []struct {
+	name        string
+	ctx         syntaxContext
+	description string
+}{
+	{"loop", contextLoop, "in loops"},
+	{"init", contextInit, "during package initialization"},
+	{"goroutine", contextGoroutine, "in goroutines"},
+	{"locked", contextLocked, "while a mutex is locked"},
+}
+
+// parseSyntaxContext returns the set of the contexts with the provided names.
+func parseSyntaxContext(names []string) (syntaxContext, error) {
+	var out syntaxContext
+	for _, name := range names {
+		found := false
+		for _, curr := range syntaxContexts {
+			if curr.name == name {
+				out |= curr.ctx
+				found = true
+			}
+		}
+		if !found {
+			var valid []string
+			for _, curr := range syntaxContexts {
+				valid = append(valid, curr.name)
+			}
+			return 0, errors.Errorf("invalid context %q: must be one of %s", name, strings.Join(valid, ", "))
+		}
+	}
+	return out, nil
+}
+
+// description returns the description of the first context in the set.
+func (s syntaxContext) description() string {
+	for _, curr := range syntaxContexts {
+		if s&curr.ctx != 0 {
+			return curr.description
+		}
+	}
+	return ""
+}
+
+// refSites records the syntax of a package that is required to describe the site of a reference.
+type refSites struct {
+	funcs enclosingFuncs
+	// contexts maps the position of each identifier to the contexts in which it appears. Nil if no rule depends on the
+	// context of a reference.
+	contexts map[token.Pos]syntaxContext
+	// discarded are the positions of the identifiers of the functions and methods that are called without using any of
+	// their results. Only populated if a rule depends on whether the results of a call are used.
+	discarded map[token.Pos]bool
+	// params are the parameters of the functions that enclose each identifier. Only populated if a rule depends on the
+	// parameters of the enclosing functions.
+	params enclosingParams
+}
+
+// newSyntaxContexts returns the contexts of the identifiers in the provided package. Identifiers that are not in any
+// context are omitted. A function literal starts a new context, except that function literals within a goroutine are
+// also in the goroutine.
+func newSyntaxContexts(pkg packageInfo) map[token.Pos]syntaxContext {
+	out := make(map[token.Pos]syntaxContext)
+	for _, file := range pkg.Files {
+		ast.PreorderStack(file, nil, func(n ast.Node, stack []ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if ctx := identContext(pkg.Info, id, stack); ctx != 0 {
+					out[id.Pos()] = ctx
+				}
+			}
+			return true
+		})
+	}
+	return out
+}
+
+// identContext returns the contexts of the provided identifier, whose ancestors are the provided stack.
+func identContext(info *types.Info, id *ast.Ident, stack []ast.Node) syntaxContext {
+	var ctx syntaxContext
+	inFuncLit := false
+	for i := len(stack) - 1; i >= 0; i-- {
+		var child ast.Node = id
+		if i+1 < len(stack) {
+			child = stack[i+1]
+		}
+		switch node := stack[i].(type) {
+		case *ast.FuncLit:
+			if i >= 2 {
+				if call, ok := stack[i-1].(*ast.CallExpr); ok && call.Fun == node {
+					if _, ok := stack[i-2].(*ast.GoStmt); ok {
+						ctx |= contextGoroutine
+					}
+				}
+			}
+			inFuncLit = true
+		}
+		if inFuncLit {
+			continue
+		}
+		switch node := stack[i].(type) {
+		case *ast.ForStmt:
+			if child != node.Init {
+				ctx |= contextLoop
+			}
+		case *ast.RangeStmt:
+			if child == node.Body {
+				ctx |= contextLoop
+			}
+		case *ast.BlockStmt:
+			if lockedBefore(info, node.List, child) {
+				ctx |= contextLocked
+			}
+		case *ast.CaseClause:
+			if lockedBefore(info, node.Body, child) {
+				ctx |= contextLocked
+			}
+		case *ast.CommClause:
+			if lockedBefore(info, node.Body, child) {
+				ctx |= contextLocked
+			}
+		case *ast.FuncDecl:
+			if node.Recv == nil && node.Name.Name == "init" && child == node.Body {
+				ctx |= contextInit
+			}
+		case *ast.GenDecl:
+			if _, ok := child.(*ast.ValueSpec); ok && node.Tok == token.VAR && i == 1 {
+				ctx |= contextInit
+			}
+		}
+	}
+	return ctx
+}
+
+// lockedBefore returns true if a mutex is locked by the statements in the provided list that precede the provided
+// statement. Mutexes are identified by the expression on which the methods are called.
+func lockedBefore(info *types.Info, stmts []ast.Stmt, stmt ast.Node) bool {
+	locked := make(map[string]struct{})
+	for _, curr := range stmts {
+		if curr == stmt {
+			return len(locked) > 0
+		}
+		exprStmt, ok := curr.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		call, ok := exprStmt.X.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		method, ok := info.Uses[sel.Sel].(*types.Func)
+		if !ok || method.Pkg() == nil || method.Pkg().Path() != "sync" {
+			continue
+		}
+		switch method.Name() {
+		case "Lock", "RLock":
+			locked[types.ExprString(sel.X)] = struct{}{}
+		case "Unlock", "RUnlock":
+			delete(locked, types.ExprString(sel.X))
+		}
+	}
+	return false
+}
+
+// whenResultDiscarded is the value of Rule.When that restricts a rule to calls whose results are discarded.
+const whenResultDiscarded = "result-discarded"
+
+// newDiscardedCalls returns the positions of the identifiers of the functions and methods in the provided package that
+// are called without using any of their results: in expression, "defer" and "go" statements and in assignments of all
+// results to the blank identifier.
+func newDiscardedCalls(pkg packageInfo) map[token.Pos]bool {
+	out := make(map[token.Pos]bool)
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var call *ast.CallExpr
+			switch n := n.(type) {
+			case *ast.ExprStmt:
+				call, _ = ast.Unparen(n.X).(*ast.CallExpr)
+			case *ast.DeferStmt:
+				call = n.Call
+			case *ast.GoStmt:
+				call = n.Call
+			case *ast.AssignStmt:
+				if len(n.Rhs) != 1 {
+					return true
+				}
+				for _, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); !ok || id.Name != "_" {
+						return true
+					}
+				}
+				call, _ = ast.Unparen(n.Rhs[0]).(*ast.CallExpr)
+			}
+			if call == nil {
+				return true
+			}
+			fun := ast.Unparen(call.Fun)
+			switch index := fun.(type) {
+			case *ast.IndexExpr:
+				fun = index.X
+			case *ast.IndexListExpr:
+				fun = index.X
+			}
+			switch fun := fun.(type) {
+			case *ast.Ident:
+				out[fun.Pos()] = true
+			case *ast.SelectorExpr:
+				out[fun.Sel.Pos()] = true
+			}
+			return true
+		})
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+type declarationMatcher struct {
+	DeclarationRule
+	name *regexp.Regexp
+	pkgs pkgPatterns
+	msg  *template.Template
+}
+
+func newDeclarationMatcher(rule DeclarationRule) (declarationMatcher, error) {
+	switch rule.Receiver {
+	case "", "function", "method", "value", "pointer":
+	default:
+		return declarationMatcher{}, errors.Errorf("invalid receiver %q: must be one of \"function\", \"method\", \"value\" or \"pointer\"", rule.Receiver)
+	}
+	if rule.Implements != "" && (rule.Name != "" || rule.Signature != "" || rule.Receiver != "") {
+		return declarationMatcher{}, errors.Errorf("implements may not be specified with name, signature or receiver")
+	}
+	if rule.Implements != "" && !strings.Contains(rule.Implements, ".") {
+		return declarationMatcher{}, errors.Errorf("implements must be a qualified name: %q", rule.Implements)
+	}
+	name := rule.Name
+	if name == "" {
+		name = "*"
+	}
+	nameRegexp, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(name), `\*`, ".*") + "$")
+	if err != nil {
+		return declarationMatcher{}, errors.Wrapf(err, "invalid name %q", rule.Name)
+	}
+	pkgs, err := newPkgPatterns(rule.Packages)
+	if err != nil {
+		return declarationMatcher{}, err
+	}
+	msg, err := newMessageTemplate(rule.Message)
+	if err != nil {
+		return declarationMatcher{}, err
+	}
+	return declarationMatcher{
+		DeclarationRule: rule,
+		name:            nameRegexp,
+		pkgs:            pkgs,
+		msg:             msg,
+	}, nil
+}
+
+// matchesFunc returns true if the provided declared function or method matches the rule.
+func (m declarationMatcher) matchesFunc(fn *types.Func) bool {
+	if m.Implements != "" || !m.name.MatchString(fn.Name()) {
+		return false
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok {
+		return false
+	}
+	if m.Signature != "" && declaredSignature(sig) != m.Signature {
+		return false
+	}
+	recv := sig.Recv()
+	switch m.Receiver {
+	case "function":
+		return recv == nil
+	case "method":
+		return recv != nil
+	case "value":
+		if recv == nil {
+			return false
+		}
+		_, isPtr := types.Unalias(recv.Type()).(*types.Pointer)
+		return !isPtr
+	case "pointer":
+		if recv == nil {
+			return false
+		}
+		_, isPtr := types.Unalias(recv.Type()).(*types.Pointer)
+		return isPtr
+	default:
+		return true
+	}
+}
+
+// declaredSignature returns the string representation of the provided signature without its receiver and parameter
+// names and with vendor directories removed from package paths, as in "func(context.Context, []byte) error".
+func declaredSignature(sig *types.Signature) string {
+	return types.TypeString(toTypeRemoveVendor(types.NewSignatureType(nil, nil, nil, newTupleNoNames(sig.Params()), newTupleNoNames(sig.Results()), sig.Variadic())), nil)
+}
+
+// finding returns the finding for a declaration that violates the rule.
+func (m declarationMatcher) finding(c *checker, ref, defaultMsg string, data MessageData) Finding {
+	data.ID = m.ID
+	data.Replacement = m.Replacement
+	data.DocURL = m.DocURL
+	return Finding{
+		Ref:         ref,
+		RuleID:      m.ID,
+		Message:     c.render(m.msg, defaultMsg, declarationWhitelistHint, data),
+		Replacement: m.Replacement,
+		DocURL:      m.DocURL,
+	}
+}
+
+// checkDeclarations returns the findings for the declarations in the provided package that violate declaration rules.
+func (c *checker) checkDeclarations(pkg packageInfo, comments map[string]map[int]string) []Finding {
+	if len(c.decls) == 0 {
+		return nil
+	}
+
+	var rules []declarationMatcher
+	for _, rule := range c.decls {
+		if rule.pkgs.matches(pkg.Path, pkg.ModulePath) {
+			rules = append(rules, rule)
+		}
+	}
+
+	var keys []*ast.Ident
+	for k, obj := range pkg.Info.Defs {
+		if obj != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Sort(identSlice(keys))
+
+	var findings []Finding
+	for _, id := range keys {
+		pos := pkg.Fset.Position(id.Pos())
+		if isWhitelisted(comments, pos) {
+			continue
+		}
+		switch obj := pkg.Info.Defs[id].(type) {
+		case *types.Func:
+			if recv := obj.Signature().Recv(); recv != nil && types.IsInterface(recv.Type()) {
+				// the methods of interfaces are not declarations of methods
+				continue
+			}
+			for _, rule := range rules {
+				if !rule.matchesFunc(obj) {
+					continue
+				}
+				sig := toFuncWithNoIdentifiersRemoveVendor(obj).String()
+				finding := rule.finding(c, sig, fmt.Sprintf("declarations of %q are not allowed.", sig), MessageData{
+					Signature: sig,
+					Package:   removeVendor(pkg.Path),
+				})
+				finding.Pos = pos
+				findings = append(findings, finding)
+				break
+			}
+		case *types.TypeName:
+			if obj.IsAlias() || types.IsInterface(obj.Type()) || obj.Parent() != obj.Pkg().Scope() {
+				continue
+			}
+			for _, rule := range rules {
+				if rule.Implements == "" {
+					continue
+				}
+				iface := lookupInterface(pkg.Types, rule.Implements)
+				if iface == nil || (!types.Implements(obj.Type(), iface) && !types.Implements(types.NewPointer(obj.Type()), iface)) {
+					continue
+				}
+				ref := "type " + qualifiedNameRemoveVendor(obj)
+				finding := rule.finding(c, ref, fmt.Sprintf("types that implement %q are not allowed.", rule.Implements), MessageData{
+					Signature: ref,
+					Package:   removeVendor(pkg.Path),
+				})
+				finding.Pos = pos
+				findings = append(findings, finding)
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// lookupInterface returns the interface with the provided qualified name (such as "context.Context") from the provided
+// package or any of its transitive imports. Returns nil if no such interface can be found.
+func lookupInterface(pkg *types.Package, qualifiedName string) *types.Interface {
+	dotIdx := strings.LastIndex(qualifiedName, ".")
+	obj, ok := lookupPackageObject(pkg, qualifiedName[:dotIdx], qualifiedName[dotIdx+1:]).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	iface, _ := obj.Type().Underlying().(*types.Interface)
+	return iface
+}
+
+// lookupPackageObject returns the package-level object with the provided name in the package with the provided path
+// (with vendor directories removed), which must be the provided package or one of its transitive imports. Returns nil
+// if no such object can be found.
+func lookupPackageObject(pkg *types.Package, pkgPath, name string) types.Object {
+	if pkg == nil {
+		return nil
+	}
+	seen := make(map[*types.Package]struct{})
+	var find func(*types.Package) types.Object
+	find = func(curr *types.Package) types.Object {
+		if _, ok := seen[curr]; ok {
+			return nil
+		}
+		seen[curr] = struct{}{}
+		if removeVendor(curr.Path()) == pkgPath {
+			return curr.Scope().Lookup(name)
+		}
+		for _, imported := range curr.Imports() {
+			if obj := find(imported); obj != nil {
+				return obj
+			}
+		}
+		return nil
+	}
+	return find(pkg)
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"text/template"
+
+	"github.com/palantir/go-nobadfuncs/nobadfuncs/internal/deprecation"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// The preset is generated from the standard library of the toolchain specified in go.mod, which must be kept in sync
+// with the version in this directive.
+//go:generate env GOTOOLCHAIN=go1.26.5 go run ./internal/gendeprecated -out presets/deprecated-stdlib.yml
+
+// deprecatedRuleID is the ID of the findings for references to deprecated objects.
+const deprecatedRuleID = "deprecated"
+
+type deprecatedMatcher struct {
+	refs refPatterns
+	pkgs pkgPatterns
+	msg  *template.Template
+}
+
+func newDeprecatedMatcher(cfg DeprecatedConfig) (*deprecatedMatcher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	refs, err := newRefPatterns(cfg.Refs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid deprecated configuration")
+	}
+	pkgs, err := newPkgPatterns(cfg.Packages)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid deprecated configuration")
+	}
+	msg, err := newMessageTemplate(cfg.Message)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid deprecated configuration")
+	}
+	return &deprecatedMatcher{
+		refs: refs,
+		pkgs: pkgs,
+		msg:  msg,
+	}, nil
+}
+
+// deprecations records the text of the "Deprecated:" paragraphs of the documentation of the package-level objects and
+// packages in the loaded packages and their dependencies.
+type deprecations struct {
+	objs map[types.Object]string
+	pkgs map[*types.Package]string
+}
+
+func newDeprecations(pkgs []*packages.Package) deprecations {
+	out := deprecations{
+		objs: make(map[types.Object]string),
+		pkgs: make(map[*types.Package]string),
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.TypesInfo == nil {
+			return
+		}
+		for _, file := range pkg.Syntax {
+			if msg, ok := deprecation.Text(file.Doc); ok {
+				out.pkgs[pkg.Types] = msg
+			}
+		}
+		visitObjectDocs(pkg, func(obj types.Object, doc *ast.CommentGroup) {
+			if msg, ok := deprecation.Text(doc); ok {
+				out.objs[obj] = msg
+			}
+		})
+	})
+	return out
+}
+
+// visitObjectDocs calls the provided function for each package-level object (and method) declared in the provided
+// package with the documentation that applies to it. The function is not called for objects without documentation.
+func visitObjectDocs(pkg *packages.Package, fn func(obj types.Object, doc *ast.CommentGroup)) {
+	visit := func(name *ast.Ident, doc *ast.CommentGroup) {
+		if obj := pkg.TypesInfo.Defs[name]; obj != nil && doc != nil {
+			fn(obj, doc)
+		}
+	}
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				visit(decl.Name, decl.Doc)
+			case *ast.GenDecl:
+				deprecation.VisitSpecs(decl, visit)
+			}
+		}
+	}
+}
+
+// lookup returns the deprecation text of the referenced object or, if the object is not deprecated, of its package.
+func (d deprecations) lookup(ref objRef) (string, bool) {
+	obj := ref.Obj
+	if fn, ok := obj.(*types.Func); ok {
+		obj = fn.Origin()
+	}
+	if msg, ok := d.objs[obj]; ok {
+		return msg, true
+	}
+	msg, ok := d.pkgs[obj.Pkg()]
+	return msg, ok
+}
+
+// checkDeprecated returns the finding for the provided reference if it refers to a deprecated object.
+func (c *checker) checkDeprecated(pkg packageInfo, ref objRef, data MessageData) (Finding, bool) {
+	if c.deprecated == nil || !c.deprecated.pkgs.matches(pkg.Path, pkg.ModulePath) {
+		return Finding{}, false
+	}
+	if len(c.deprecated.refs) > 0 && !c.deprecated.refs.matches(ref) {
+		return Finding{}, false
+	}
+	msg, ok := c.deprecations.lookup(ref)
+	if !ok {
+		return Finding{}, false
+	}
+	return c.ruleFinding(ref.Sig, deprecatedRuleID, msg, "", c.deprecated.msg, fmt.Sprintf("%q is deprecated: %s", ref.Sig, msg), data), true
+}
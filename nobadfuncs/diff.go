@@ -0,0 +1,197 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"bufio"
+	"bytes"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// changedLines is a map from absolute file path to the set of line numbers that were added or modified in the file. A
+// nil set indicates that the entire file is new.
+type changedLines map[string]map[int]struct{}
+
+func (c changedLines) contains(pos token.Position) bool {
+	lines, ok := c[filepath.Clean(pos.Filename)]
+	if !ok {
+		return false
+	}
+	if lines == nil {
+		return true
+	}
+	_, ok = lines[pos.Line]
+	return ok
+}
+
+// newChangedLines returns the changed lines specified by the provided options. Returns nil if the options do not
+// restrict the check to changed lines.
+func newChangedLines(opts Options, dir string) (changedLines, error) {
+	switch {
+	case opts.NewFromRev != "" && opts.NewFromPatch != "":
+		return nil, errors.Errorf("at most one of NewFromRev and NewFromPatch may be specified")
+	case opts.NewFromRev != "":
+		return gitChangedLines(opts.NewFromRev, dir)
+	case opts.NewFromPatch != "":
+		f, err := os.Open(opts.NewFromPatch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open patch file")
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		return parseUnifiedDiff(f, dir)
+	default:
+		return nil, nil
+	}
+}
+
+// gitChangedLines returns the lines in the working tree of the git repository that contains dir that were added or
+// modified relative to the provided revision. All lines of untracked files are considered to be added.
+func gitChangedLines(rev, dir string) (changedLines, error) {
+	root, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = strings.TrimSpace(root)
+
+	// the prefixes are specified explicitly because configuration such as diff.noprefix and diff.mnemonicPrefix changes them
+	diff, err := runGit(dir, "diff", "--no-color", "--no-ext-diff", "--no-renames", "--src-prefix=a/", "--dst-prefix=b/", "-U0", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	out, err := parseUnifiedDiff(strings.NewReader(diff), root)
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := runGit(root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(untracked, "\n") {
+		if name == "" {
+			continue
+		}
+		out[filepath.Join(root, name)] = nil
+	}
+	return out, nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// diffFileName returns the file name of a "---" or "+++" header of a unified diff without the header and timestamp.
+func diffFileName(header string) string {
+	if tabIdx := strings.IndexByte(header, '\t'); tabIdx != -1 {
+		header = header[:tabIdx]
+	}
+	return header
+}
+
+// matches the header of a hunk in a unified diff and captures the start line and line count of the new file.
+var hunkHeaderRegexp = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff returns the lines that are added by the provided unified diff. File paths in the diff are resolved
+// relative to root. Files that are deleted by the diff are ignored. The "b/" prefix of the new file name is only removed
+// if the diff uses the "a/" and "b/" prefixes of git (that is, if the old file name or the "diff --git" header of the
+// file uses the "a/" prefix), so that diffs created with "--no-prefix" are also supported.
+func parseUnifiedDiff(r io.Reader, root string) (changedLines, error) {
+	out := make(changedLines)
+	var currLines map[int]struct{}
+	currLine := 0
+	remaining := 0
+	// gitPrefixed is true if the "diff --git" header of the current file uses the "a/" prefix
+	gitPrefixed := false
+	// oldName is the old file name of the current file
+	oldName := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case remaining == 0 && strings.HasPrefix(line, "diff --git "):
+			gitPrefixed = strings.HasPrefix(line, "diff --git a/")
+			oldName = ""
+		case remaining == 0 && strings.HasPrefix(line, "--- "):
+			oldName = diffFileName(strings.TrimPrefix(line, "--- "))
+		case remaining == 0 && strings.HasPrefix(line, "+++ "):
+			currLines = nil
+			name := diffFileName(strings.TrimPrefix(line, "+++ "))
+			if name == "/dev/null" {
+				continue
+			}
+			if strings.HasPrefix(oldName, "a/") || (oldName == "/dev/null" && gitPrefixed) {
+				name = strings.TrimPrefix(name, "b/")
+			}
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(root, name)
+			}
+			name = filepath.Clean(name)
+			if out[name] == nil {
+				out[name] = make(map[int]struct{})
+			}
+			currLines = out[name]
+		case remaining == 0 && strings.HasPrefix(line, "@@ "):
+			matches := hunkHeaderRegexp.FindStringSubmatch(line)
+			if matches == nil {
+				return nil, errors.Errorf("invalid hunk header: %q", line)
+			}
+			start, err := strconv.Atoi(matches[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid hunk header: %q", line)
+			}
+			count := 1
+			if matches[2] != "" {
+				if count, err = strconv.Atoi(matches[2]); err != nil {
+					return nil, errors.Wrapf(err, "invalid hunk header: %q", line)
+				}
+			}
+			currLine, remaining = start, count
+		case remaining > 0 && strings.HasPrefix(line, "+"):
+			if currLines != nil {
+				currLines[currLine] = struct{}{}
+			}
+			currLine++
+			remaining--
+		case remaining > 0 && (strings.HasPrefix(line, " ") || line == ""):
+			currLine++
+			remaining--
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read diff")
+	}
+	return out, nil
+}
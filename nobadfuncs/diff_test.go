@@ -0,0 +1,183 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diffTestOldSrc = `package foo
+
+import (
+	"net/http"
+)
+
+func Foo() {
+	http.DefaultClient.Do(nil)
+}
+`
+
+const diffTestNewSrc = `package foo
+
+import (
+	"net/http"
+)
+
+func Foo() {
+	http.DefaultClient.Do(nil)
+}
+
+func Bar() {
+	http.DefaultClient.Do(nil)
+}
+`
+
+func TestPrintBadFuncRefsNewFrom(t *testing.T) {
+	sigs := map[string]string{
+		"func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)": "No",
+	}
+
+	t.Run("new from patch", func(t *testing.T) {
+		projectDir := t.TempDir()
+		_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.mod",
+				Src:     "module github.com/palantir/go-nobadfuncs-test",
+			},
+			{
+				RelPath: "foo/foo.go",
+				Src:     diffTestNewSrc,
+			},
+			{
+				RelPath: "change.patch",
+				Src: `diff --git a/foo/foo.go b/foo/foo.go
+--- a/foo/foo.go
++++ b/foo/foo.go
+@@ -8,2 +8,6 @@ func Foo() {
+ 	http.DefaultClient.Do(nil)
+ }
++
++func Bar() {
++	http.DefaultClient.Do(nil)
++}
+`,
+			},
+		})
+		require.NoError(t, err)
+
+		var got bytes.Buffer
+		err = nobadfuncs.PrintBadRefs([]string{"./..."}, nobadfuncs.Config{Funcs: sigs}, projectDir, nobadfuncs.Options{
+			NewFromPatch: path.Join(projectDir, "change.patch"),
+		}, &got)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf("%s:12:21: No\n", path.Join(projectDir, "foo/foo.go")), got.String())
+	})
+
+	t.Run("new from patch without prefixes", func(t *testing.T) {
+		projectDir := t.TempDir()
+		_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.mod",
+				Src:     "module github.com/palantir/go-nobadfuncs-test",
+			},
+			{
+				RelPath: "b/foo/foo.go",
+				Src:     diffTestNewSrc,
+			},
+			{
+				RelPath: "change.patch",
+				Src: `diff --git b/foo/foo.go b/foo/foo.go
+--- b/foo/foo.go
++++ b/foo/foo.go
+@@ -8,2 +8,6 @@ func Foo() {
+ 	http.DefaultClient.Do(nil)
+ }
++
++func Bar() {
++	http.DefaultClient.Do(nil)
++}
+`,
+			},
+		})
+		require.NoError(t, err)
+
+		var got bytes.Buffer
+		err = nobadfuncs.PrintBadRefs([]string{"./..."}, nobadfuncs.Config{Funcs: sigs}, projectDir, nobadfuncs.Options{
+			NewFromPatch: path.Join(projectDir, "change.patch"),
+		}, &got)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf("%s:12:21: No\n", path.Join(projectDir, "b/foo/foo.go")), got.String())
+	})
+
+	t.Run("new from rev", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not available")
+		}
+
+		projectDir := t.TempDir()
+		_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.mod",
+				Src:     "module github.com/palantir/go-nobadfuncs-test",
+			},
+			{
+				RelPath: "foo/foo.go",
+				Src:     diffTestOldSrc,
+			},
+		})
+		require.NoError(t, err)
+
+		for _, args := range [][]string{
+			{"init", "-q"},
+			// the prefixes of the diff must not depend on the configuration of the repository
+			{"config", "diff.mnemonicPrefix", "true"},
+			{"add", "."},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = projectDir
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(output))
+		}
+
+		_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+			{
+				RelPath: "foo/foo.go",
+				Src:     diffTestNewSrc,
+			},
+			{
+				RelPath: "foo/untracked.go",
+				Src:     "package foo\n\nimport \"net/http\"\n\nvar _, _ = http.DefaultClient.Do(nil)\n",
+			},
+		})
+		require.NoError(t, err)
+
+		var got bytes.Buffer
+		err = nobadfuncs.PrintBadRefs([]string{"./..."}, nobadfuncs.Config{Funcs: sigs}, projectDir, nobadfuncs.Options{
+			NewFromRev: "HEAD",
+		}, &got)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf("%s:12:21: No\n%s:5:31: No\n", path.Join(projectDir, "foo/foo.go"), path.Join(projectDir, "foo/untracked.go")), got.String())
+	})
+}
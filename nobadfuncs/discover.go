@@ -0,0 +1,217 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// ConfigFileName is the name of the configuration files that are discovered when Options.DiscoverConfig is true.
+const ConfigFileName = ".nobadfuncs.yml"
+
+// configHierarchy computes the effective configuration of packages from a base configuration and the configuration
+// files in the directories of the packages and their parents.
+type configHierarchy struct {
+	// base is the resolved base configuration.
+	base Config
+	// workingDirs are the working directory and its parents whose configuration files apply to all packages (see
+	// workingDirs).
+	workingDirs []string
+	// files caches the configuration file in each directory. The value is nil if the directory does not have one.
+	files map[string]*Config
+	// configs caches the effective configuration of each package directory.
+	configs map[string]hierarchyConfig
+}
+
+// hierarchyConfig is the effective configuration of a directory.
+type hierarchyConfig struct {
+	cfg Config
+	// key identifies the configuration files that apply to the directory. Directories with the same key have the same
+	// effective configuration. Empty if no configuration files apply.
+	key string
+}
+
+// newConfigHierarchy returns the hierarchy for the provided resolved base configuration. dir is the working directory.
+func newConfigHierarchy(base Config, dir string) *configHierarchy {
+	return &configHierarchy{
+		base:        base,
+		workingDirs: workingDirs(dir),
+		files:       make(map[string]*Config),
+		configs:     make(map[string]hierarchyConfig),
+	}
+}
+
+// workingDirs returns the provided working directory and each of its parents up to the first directory that contains a
+// "go.mod" or "go.work" file (the root of the module or workspace), in order from the outermost directory. If there is
+// no such directory, only the working directory is returned.
+func workingDirs(dir string) []string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for curr := dir; ; curr = filepath.Dir(curr) {
+		out = append(out, curr)
+		if fileExists(filepath.Join(curr, "go.mod")) || fileExists(filepath.Join(curr, "go.work")) {
+			break
+		}
+		if filepath.Dir(curr) == curr {
+			// no module or workspace root
+			out = []string{dir}
+			break
+		}
+	}
+	slices.Reverse(out)
+	return out
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// configFor returns the effective configuration of the provided package. The configuration files in the working
+// directory and its parents (see workingDirs) apply to all packages. They are followed by the configuration files in
+// the module root directory of the package and each of its subdirectories up to the directory of the package (or, for
+// packages that are not in a module, only the file in the directory of the package). The files are resolved on top of
+// the base configuration in that order, skipping directories that were already visited, so files in deeper directories
+// take precedence and their overrides apply to the rules of the files above them. The file from which the base
+// configuration was loaded (if any) is skipped because it is already applied.
+func (h *configHierarchy) configFor(pkg *packages.Package) (hierarchyConfig, error) {
+	if out, ok := h.configs[pkg.Dir]; ok {
+		return out, nil
+	}
+	dirs := slices.Clone(h.workingDirs)
+	var pkgDirs []string
+	if pkg.Dir != "" {
+		pkgDirs = []string{pkg.Dir}
+	}
+	if pkg.Dir != "" && pkg.Module != nil && pkg.Module.Dir != "" {
+		if rel, err := filepath.Rel(pkg.Module.Dir, pkg.Dir); err == nil && !strings.HasPrefix(rel, "..") {
+			pkgDirs = []string{pkg.Module.Dir}
+			if rel != "." {
+				curr := pkg.Module.Dir
+				for _, part := range strings.Split(rel, string(filepath.Separator)) {
+					curr = filepath.Join(curr, part)
+					pkgDirs = append(pkgDirs, curr)
+				}
+			}
+		}
+	}
+	for _, dir := range pkgDirs {
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	out := hierarchyConfig{cfg: h.base}
+	var paths []string
+	for _, dir := range dirs {
+		fileCfg, err := h.file(dir)
+		if err != nil {
+			return hierarchyConfig{}, err
+		}
+		path := filepath.Join(dir, ConfigFileName)
+		if fileCfg == nil || path == h.base.path {
+			continue
+		}
+		if out.cfg, err = fileCfg.resolve(out.cfg, dir, []string{path}); err != nil {
+			return hierarchyConfig{}, errors.Wrapf(err, "failed to resolve configuration file %s", path)
+		}
+		paths = append(paths, path)
+	}
+	out.key = strings.Join(paths, string(filepath.ListSeparator))
+	h.configs[pkg.Dir] = out
+	return out, nil
+}
+
+// scan returns true if any configuration files apply to the provided packages and whether the effective configuration
+// of any of them has sequence rules. Only the names, files and modules of the packages are loaded, so this is much
+// cheaper than loading their syntax and types, which can be skipped if no configuration applies.
+func (h *configHierarchy) scan(pkgs []string, dir string) (applies, sequences bool, err error) {
+	loadedPkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedModule,
+		Dir:  dir,
+	}, pkgs...)
+	if err != nil {
+		return false, false, errors.Wrapf(err, "failed to load packages")
+	}
+	for _, pkg := range loadedPkgs {
+		effective, err := h.configFor(pkg)
+		if err != nil {
+			return false, false, err
+		}
+		applies = applies || effective.key != ""
+		sequences = sequences || len(effective.cfg.Sequences) > 0
+	}
+	return applies, sequences, nil
+}
+
+// file returns the configuration file in the provided directory. Returns nil if the directory does not have one.
+func (h *configHierarchy) file(dir string) (*Config, error) {
+	if cfg, ok := h.files[dir]; ok {
+		return cfg, nil
+	}
+	path := filepath.Join(dir, ConfigFileName)
+	var cfg *Config
+	if _, err := os.Stat(path); err == nil {
+		fileCfg, err := readConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = &fileCfg
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to check for configuration file %s", path)
+	}
+	h.files[dir] = cfg
+	return cfg, nil
+}
+
+// EffectiveConfig returns the resolved configuration that applies to the single package matched by the provided
+// pattern when the provided configuration is checked with the provided options. Discovered configuration files are only
+// applied if Options.DiscoverConfig is true.
+func EffectiveConfig(pkg string, cfg Config, dir string, opts Options) (Config, error) {
+	cfg, err := cfg.Resolve(dir)
+	if err != nil {
+		return Config{}, err
+	}
+	loadedPkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedModule,
+		Dir:  dir,
+	}, pkg)
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to load packages")
+	}
+	if len(loadedPkgs) != 1 {
+		return Config{}, errors.Errorf("pattern %q must match exactly 1 package, but matched %d", pkg, len(loadedPkgs))
+	}
+	for _, err := range loadedPkgs[0].Errors {
+		return Config{}, errors.Errorf("failed to load package %s: %s", pkg, err.Msg)
+	}
+	if !opts.DiscoverConfig {
+		return cfg, nil
+	}
+	effective, err := newConfigHierarchy(cfg, dir).configFor(loadedPkgs[0])
+	if err != nil {
+		return Config{}, err
+	}
+	return effective.cfg, nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintBadRefsDiscoverConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: ".nobadfuncs.yml",
+			Src: `deny:
+  - id: no-exit
+    refs: ["os.Exit"]
+  - id: no-getenv
+    refs: ["os.Getenv"]
+`,
+		},
+		{
+			RelPath: "cmd/.nobadfuncs.yml",
+			Src: `overrides:
+  - id: no-exit
+    disabled: true
+`,
+		},
+		{
+			RelPath: "internal/.nobadfuncs.yml",
+			Src: `deny:
+  - id: no-println
+    refs: ["fmt.Println"]
+`,
+		},
+		{
+			RelPath: "cmd/main.go",
+			Src: `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println(os.Getenv("FOO"))
+	os.Exit(1)
+}
+`,
+		},
+		{
+			RelPath: "internal/foo/foo.go",
+			Src: `package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+func Foo() {
+	fmt.Println(os.Getenv("FOO"))
+	os.Exit(1)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	var got bytes.Buffer
+	_ = nobadfuncs.PrintBadRefs([]string{"./..."}, nobadfuncs.Config{}, projectDir, nobadfuncs.Options{
+		DiscoverConfig: true,
+	}, &got)
+	hint := " Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it."
+	assert.Equal(t, strings.Join([]string{
+		fmt.Sprintf(`%s:9:17: references to "func os.Getenv(string) string" are not allowed.`+hint, path.Join(projectDir, "cmd/main.go")),
+		fmt.Sprintf(`%s:9:6: references to "func fmt.Println(...any) (int, error)" are not allowed.`+hint, path.Join(projectDir, "internal/foo/foo.go")),
+		fmt.Sprintf(`%s:9:17: references to "func os.Getenv(string) string" are not allowed.`+hint, path.Join(projectDir, "internal/foo/foo.go")),
+		fmt.Sprintf(`%s:10:5: references to "func os.Exit(int)" are not allowed.`+hint, path.Join(projectDir, "internal/foo/foo.go")),
+	}, "\n")+"\n", got.String())
+
+	t.Run("effective configuration", func(t *testing.T) {
+		cfg, err := nobadfuncs.EffectiveConfig("./cmd", nobadfuncs.Config{}, projectDir, nobadfuncs.Options{DiscoverConfig: true})
+		require.NoError(t, err)
+		assert.Equal(t, nobadfuncs.Config{
+			Deny: []nobadfuncs.Rule{
+				{
+					ID:   "no-getenv",
+					Refs: []string{"os.Getenv"},
+				},
+			},
+		}, cfg)
+	})
+
+	t.Run("effective configuration without discovery", func(t *testing.T) {
+		cfg := nobadfuncs.Config{
+			Deny: []nobadfuncs.Rule{
+				{
+					ID:   "no-println",
+					Refs: []string{"fmt.Println"},
+				},
+			},
+		}
+		effective, err := nobadfuncs.EffectiveConfig("./cmd", cfg, projectDir, nobadfuncs.Options{})
+		require.NoError(t, err)
+		assert.Equal(t, cfg, effective)
+	})
+
+	t.Run("configuration files in working directory and its parents", func(t *testing.T) {
+		// -mod=mod is not supported in workspace mode
+		t.Setenv("GOFLAGS", "")
+		dir := t.TempDir()
+		_, err := gofiles.Write(dir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.work",
+				Src:     "go 1.26.0\n\nuse ./mod\n",
+			},
+			{
+				RelPath: ".nobadfuncs.yml",
+				Src: `deny:
+  - id: no-exit
+    refs: ["os.Exit"]
+`,
+			},
+			{
+				RelPath: "mod/go.mod",
+				Src:     "module github.com/palantir/go-nobadfuncs-test\n\ngo 1.26.0\n",
+			},
+			{
+				RelPath: "mod/tools/.nobadfuncs.yml",
+				Src: `deny:
+  - id: no-getenv
+    refs: ["os.Getenv"]
+`,
+			},
+			{
+				RelPath: "mod/foo/foo.go",
+				Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(len(os.Getenv("FOO")))
+}
+`,
+			},
+		})
+		require.NoError(t, err)
+
+		// the file in the workspace root applies to all packages
+		findings, err := nobadfuncs.Check([]string{"./mod/..."}, nobadfuncs.Config{}, dir, nobadfuncs.Options{DiscoverConfig: true})
+		require.NoError(t, err)
+		var got []string
+		for _, finding := range findings {
+			got = append(got, finding.RuleID)
+		}
+		assert.Equal(t, []string{"no-exit"}, got)
+
+		// the files in the working directory and its parents up to the module root apply to packages outside of the
+		// working directory
+		findings, err = nobadfuncs.Check([]string{"../foo"}, nobadfuncs.Config{}, path.Join(dir, "mod", "tools"), nobadfuncs.Options{DiscoverConfig: true})
+		require.NoError(t, err)
+		got = nil
+		for _, finding := range findings {
+			got = append(got, finding.RuleID)
+		}
+		assert.Equal(t, []string{"no-getenv"}, got)
+	})
+
+	t.Run("discovered configuration files on top of explicit configuration", func(t *testing.T) {
+		cfg, err := nobadfuncs.ParseConfig([]byte(`deny:
+  - id: no-exit-explicit
+    refs: ["os.Exit"]
+`))
+		require.NoError(t, err)
+		findings, err := nobadfuncs.Check([]string{"./cmd"}, cfg, projectDir, nobadfuncs.Options{DiscoverConfig: true})
+		require.NoError(t, err)
+		var got []string
+		for _, finding := range findings {
+			got = append(got, finding.RuleID)
+		}
+		// the override in cmd/.nobadfuncs.yml only disables the inherited rule with its ID
+		assert.Equal(t, []string{"no-getenv", "no-exit-explicit"}, got)
+	})
+
+	t.Run("explicit configuration file is not applied again when discovered", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := gofiles.Write(dir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.mod",
+				Src:     `module github.com/palantir/go-nobadfuncs-test`,
+			},
+			{
+				RelPath: ".nobadfuncs.yml",
+				Src: `sequences:
+  - calls: ["fmt.Println"]
+    not-after: ["os.Exit"]
+`,
+			},
+			{
+				RelPath: "foo/foo.go",
+				Src: `package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+func Foo() {
+	os.Exit(1)
+	fmt.Println()
+}
+`,
+			},
+		})
+		require.NoError(t, err)
+		cfg, err := nobadfuncs.LoadConfig(path.Join(dir, ".nobadfuncs.yml"))
+		require.NoError(t, err)
+		effective, err := nobadfuncs.EffectiveConfig("./foo", cfg, dir, nobadfuncs.Options{DiscoverConfig: true})
+		require.NoError(t, err)
+		assert.Len(t, effective.Sequences, 1)
+	})
+
+	t.Run("no configuration", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := gofiles.Write(dir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.mod",
+				Src:     `module github.com/palantir/go-nobadfuncs-test`,
+			},
+			{
+				RelPath: "foo/foo.go",
+				Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+}
+`,
+			},
+		})
+		require.NoError(t, err)
+		findings, err := nobadfuncs.Check([]string{"./..."}, nobadfuncs.Config{}, dir, nobadfuncs.Options{DiscoverConfig: true})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	})
+
+	t.Run("invalid override in nested configuration", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := gofiles.Write(dir, []gofiles.GoFileSpec{
+			{
+				RelPath: "go.mod",
+				Src:     `module github.com/palantir/go-nobadfuncs-test`,
+			},
+			{
+				RelPath: "foo/.nobadfuncs.yml",
+				Src: `overrides:
+  - id: unknown
+    disabled: true
+`,
+			},
+			{
+				RelPath: "foo/foo.go",
+				Src:     `package foo`,
+			},
+		})
+		require.NoError(t, err)
+		_, err = nobadfuncs.Check([]string{"./..."}, nobadfuncs.Config{}, dir, nobadfuncs.Options{DiscoverConfig: true})
+		assert.EqualError(t, err, fmt.Sprintf(`failed to resolve configuration file %s: override for unknown rule "unknown"`, path.Join(dir, "foo", ".nobadfuncs.yml")))
+	})
+}
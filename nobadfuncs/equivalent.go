@@ -0,0 +1,133 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// identSelections returns a map from the selector identifier of every selector expression in the package to its
+// selection. Qualified identifiers (references to package-level objects of imported packages) are not included.
+func identSelections(pkg packageInfo) map[*ast.Ident]*types.Selection {
+	out := make(map[*ast.Ident]*types.Selection)
+	for selExpr, selection := range pkg.Info.Selections {
+		out[selExpr.Sel] = selection
+	}
+	return out
+}
+
+// equivalentRefs returns the equivalent forms of the provided reference as configured by the MatchConfig of the
+// checker. selection is the selection through which the object is referenced, if any.
+func (c *checker) equivalentRefs(ref objRef, selection *types.Selection) []objRef {
+	var out []objRef
+	addRef := func(obj types.Object) {
+		if alt, ok := newObjRef(obj); ok && alt.Sig != ref.Sig {
+			out = append(out, alt)
+		}
+	}
+
+	if c.match.Aliases {
+		switch obj := ref.Obj.(type) {
+		case *types.TypeName:
+			if obj.IsAlias() {
+				if named, ok := types.Unalias(obj.Type()).(*types.Named); ok {
+					addRef(named.Obj())
+				}
+			}
+		case *types.Func:
+			addRef(toFuncUnalias(obj.Origin()))
+		}
+	}
+
+	if c.match.PromotedMethods && selection != nil && len(selection.Index()) > 1 {
+		if method, ok := selection.Obj().(*types.Func); ok && selection.Kind() != types.FieldVal {
+			recv := selection.Recv()
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			for _, recvType := range []types.Type{recv, types.NewPointer(recv)} {
+				addRef(promotedMethod(method, recvType))
+			}
+		}
+	}
+
+	if c.match.DefinedTypes {
+		switch obj := ref.Obj.(type) {
+		case *types.TypeName:
+			for from := c.definedFrom[obj]; from != nil; from = c.definedFrom[from] {
+				addRef(from)
+			}
+		case *types.Func:
+			sig, _ := obj.Type().(*types.Signature)
+			if sig == nil || sig.Recv() == nil {
+				break
+			}
+			recv := types.Unalias(sig.Recv().Type())
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = types.Unalias(ptr.Elem())
+			}
+			named, ok := recv.(*types.Named)
+			if !ok {
+				break
+			}
+			for from := c.definedFrom[named.Origin().Obj()]; from != nil; from = c.definedFrom[from] {
+				methodObj, _, _ := types.LookupFieldOrMethod(types.NewPointer(from.Type()), false, obj.Pkg(), obj.Name())
+				if method, ok := methodObj.(*types.Func); ok {
+					addRef(method)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// promotedMethod returns a function that has the same signature as the provided method but whose receiver is of the
+// provided type.
+func promotedMethod(method *types.Func, recvType types.Type) *types.Func {
+	sig := method.Type().(*types.Signature)
+	recv := types.NewVar(sig.Recv().Pos(), sig.Recv().Pkg(), "", recvType)
+	return types.NewFunc(method.Pos(), method.Pkg(), method.Name(), types.NewSignatureType(recv, nil, nil, sig.Params(), sig.Results(), sig.Variadic()))
+}
+
+// definedFromTypes returns a map from each type in the provided packages and their dependencies that is defined from
+// another named type (as in "type MyClient net/http.Client") to the type it is defined from.
+func definedFromTypes(pkgs []*packages.Package) map[*types.TypeName]*types.TypeName {
+	out := make(map[*types.TypeName]*types.TypeName)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.TypesInfo == nil {
+			return
+		}
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				spec, ok := n.(*ast.TypeSpec)
+				if !ok || spec.Assign.IsValid() {
+					return true
+				}
+				typeName, ok := pkg.TypesInfo.Defs[spec.Name].(*types.TypeName)
+				if !ok {
+					return true
+				}
+				if from, ok := types.Unalias(pkg.TypesInfo.TypeOf(spec.Type)).(*types.Named); ok {
+					out[typeName] = from.Origin().Obj()
+				}
+				return true
+			})
+		}
+	})
+	return out
+}
@@ -0,0 +1,181 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+type fieldMatcher struct {
+	FieldRule
+	pkgPath  string
+	typeName string
+	name     string
+	op       token.Token
+	value    constant.Value
+	pkgs     pkgPatterns
+	msg      *template.Template
+}
+
+func newFieldMatcher(rule FieldRule) (fieldMatcher, error) {
+	lastDot := strings.LastIndex(rule.Field, ".")
+	typeDot := strings.LastIndex(rule.Field[:max(lastDot, 0)], ".")
+	if lastDot == -1 || typeDot == -1 || strings.LastIndex(rule.Field, "/") > typeDot {
+		return fieldMatcher{}, errors.Errorf("field must be of the form \"path/to/pkg.Type.Field\": %q", rule.Field)
+	}
+	var op token.Token
+	var valueStr string
+	switch {
+	case rule.Equals != "" && rule.LessThan == "":
+		op, valueStr = token.EQL, rule.Equals
+	case rule.LessThan != "" && rule.Equals == "":
+		op, valueStr = token.LSS, rule.LessThan
+	default:
+		return fieldMatcher{}, errors.Errorf("exactly one of equals or less-than must be specified")
+	}
+	value, err := parseConstant(valueStr)
+	if err != nil {
+		return fieldMatcher{}, err
+	}
+	if op == token.LSS && value.Kind() != constant.Int && value.Kind() != constant.Float {
+		return fieldMatcher{}, errors.Errorf("less-than must be a number: %q", valueStr)
+	}
+	pkgs, err := newPkgPatterns(rule.Packages)
+	if err != nil {
+		return fieldMatcher{}, err
+	}
+	msg, err := newMessageTemplate(rule.Message)
+	if err != nil {
+		return fieldMatcher{}, err
+	}
+	return fieldMatcher{
+		FieldRule: rule,
+		pkgPath:   rule.Field[:typeDot],
+		typeName:  rule.Field[typeDot+1 : lastDot],
+		name:      rule.Field[lastDot+1:],
+		op:        op,
+		value:     value,
+		pkgs:      pkgs,
+		msg:       msg,
+	}, nil
+}
+
+// parseConstant parses the provided string as a boolean, number or (quoted or unquoted) string constant.
+func parseConstant(in string) (constant.Value, error) {
+	switch in {
+	case "true", "false":
+		return constant.MakeBool(in == "true"), nil
+	}
+	for _, tok := range []token.Token{token.INT, token.FLOAT} {
+		if value := constant.MakeFromLiteral(in, tok, 0); value.Kind() != constant.Unknown {
+			return value, nil
+		}
+	}
+	if unquoted, err := strconv.Unquote(in); err == nil {
+		return constant.MakeString(unquoted), nil
+	}
+	return constant.MakeString(in), nil
+}
+
+// matches returns true if the provided constant value violates the rule.
+func (m fieldMatcher) matches(value constant.Value) bool {
+	if value == nil || value.Kind() == constant.Unknown {
+		return false
+	}
+	switch {
+	case value.Kind() == m.value.Kind():
+	case (value.Kind() == constant.Int || value.Kind() == constant.Float) && (m.value.Kind() == constant.Int || m.value.Kind() == constant.Float):
+	default:
+		return false
+	}
+	return constant.Compare(value, m.op, m.value)
+}
+
+// checkFields returns the findings for the assignments of constant values to struct fields in the provided package
+// that violate field rules. Both keyed elements of composite literals and assignment statements are checked.
+func (c *checker) checkFields(pkg packageInfo, comments map[string]map[int]string, funcs enclosingFuncs) []Finding {
+	fields := make(map[*types.Var][]fieldMatcher)
+	for _, rule := range c.fields {
+		if !rule.pkgs.matches(pkg.Path, pkg.ModulePath) {
+			continue
+		}
+		typeName, ok := lookupPackageObject(pkg.Types, rule.pkgPath, rule.typeName).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		obj, _, _ := types.LookupFieldOrMethod(typeName.Type(), true, typeName.Pkg(), rule.name)
+		if field, ok := obj.(*types.Var); ok && field.IsField() {
+			fields[field] = append(fields[field], rule)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	checkAssign := func(fieldIdent *ast.Ident, valueExpr ast.Expr) {
+		field, ok := pkg.Info.Uses[fieldIdent].(*types.Var)
+		if !ok {
+			return
+		}
+		for _, rule := range fields[field] {
+			if !rule.matches(pkg.Info.Types[valueExpr].Value) {
+				continue
+			}
+			pos := pkg.Fset.Position(fieldIdent.Pos())
+			if isWhitelisted(comments, pos) {
+				return
+			}
+			finding := c.ruleFinding(rule.Field, rule.ID, rule.Replacement, rule.DocURL, rule.msg, fmt.Sprintf("%s may not be set to %s.", rule.Field, pkg.Info.Types[valueExpr].Value), MessageData{
+				Signature: rule.Field,
+				Caller:    funcs.name(fieldIdent.Pos()),
+				Package:   removeVendor(pkg.Path),
+			})
+			finding.Pos = pos
+			findings = append(findings, finding)
+			return
+		}
+	}
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.KeyValueExpr:
+				if key, ok := n.Key.(*ast.Ident); ok {
+					checkAssign(key, n.Value)
+				}
+			case *ast.AssignStmt:
+				if len(n.Lhs) != len(n.Rhs) {
+					return true
+				}
+				for i, lhs := range n.Lhs {
+					if sel, ok := lhs.(*ast.SelectorExpr); ok {
+						checkAssign(sel.Sel, n.Rhs[i])
+					}
+				}
+			}
+			return true
+		})
+	}
+	return findings
+}
@@ -0,0 +1,263 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// snippetContext is the number of lines before and after the line of a finding that are included in its snippet.
+const snippetContext = 2
+
+// htmlReport is the data of the HTML report template.
+type htmlReport struct {
+	Findings     []htmlFinding
+	Suppressions []htmlFinding
+	Signatures   []reportCount
+	Packages     []reportCount
+	Owners       []reportCount
+}
+
+// htmlFinding is a finding and the snippet of the source around it.
+type htmlFinding struct {
+	Finding
+	Snippet []snippetLine
+}
+
+// snippetLine is a line of the source snippet of a finding. For the line of the finding, Match is the identifier at the
+// position of the finding and Before and After are the text around it. For all other lines, the text is in Before.
+type snippetLine struct {
+	Num    int
+	Before string
+	Match  string
+	After  string
+}
+
+// reportCount is the number of findings and suppressed findings for a signature, package or owner.
+type reportCount struct {
+	Name       string
+	Findings   int
+	Suppressed int
+}
+
+// writeHTMLReport writes a self-contained HTML report of the provided findings to w. The report contains the number of
+// findings and suppressed findings per signature, package and (if any finding has owners) owner, the findings with a
+// snippet of the source around them and the suppressed findings with the reasons for their suppression. Snippets are
+// read from the files of the findings and are omitted if a file cannot be read.
+func writeHTMLReport(findings []Finding, w io.Writer) error {
+	var report htmlReport
+	signatures := make(map[string]*reportCount)
+	pkgs := make(map[string]*reportCount)
+	owners := make(map[string]*reportCount)
+	count := func(counts map[string]*reportCount, name string, suppressed bool) {
+		c := counts[name]
+		if c == nil {
+			c = &reportCount{Name: name}
+			counts[name] = c
+		}
+		if suppressed {
+			c.Suppressed++
+		} else {
+			c.Findings++
+		}
+	}
+
+	files := make(map[string][]string)
+	for _, finding := range findings {
+		suppressed := finding.Suppression != ""
+		count(signatures, finding.Ref, suppressed)
+		count(pkgs, finding.Package, suppressed)
+		for _, owner := range finding.Owners {
+			count(owners, owner, suppressed)
+		}
+		if suppressed {
+			report.Suppressions = append(report.Suppressions, htmlFinding{Finding: finding})
+			continue
+		}
+		lines, ok := files[finding.Pos.Filename]
+		if !ok {
+			if content, err := os.ReadFile(finding.Pos.Filename); err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+			files[finding.Pos.Filename] = lines
+		}
+		report.Findings = append(report.Findings, htmlFinding{
+			Finding: finding,
+			Snippet: snippet(lines, finding.Pos.Line, finding.Pos.Column),
+		})
+	}
+	report.Signatures = sortedCounts(signatures)
+	report.Packages = sortedCounts(pkgs)
+	report.Owners = sortedCounts(owners)
+
+	if err := htmlReportTemplate.Execute(w, report); err != nil {
+		return errors.Wrapf(err, "failed to write HTML report")
+	}
+	return nil
+}
+
+// snippet returns the lines around the provided line (1-based) of a file. The identifier that begins at the provided
+// column (1-based, in bytes) of the line is returned as the Match of its line. Returns nil if the line is not in the
+// file.
+func snippet(lines []string, line, column int) []snippetLine {
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	var out []snippetLine
+	for i := max(line-snippetContext, 1); i <= min(line+snippetContext, len(lines)); i++ {
+		text := strings.TrimRight(lines[i-1], "\r")
+		if i != line || column < 1 || column > len(text) {
+			out = append(out, snippetLine{Num: i, Before: text})
+			continue
+		}
+		start := column - 1
+		end := start
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			end += size
+		}
+		out = append(out, snippetLine{
+			Num:    i,
+			Before: text[:start],
+			Match:  text[start:end],
+			After:  text[end:],
+		})
+	}
+	return out
+}
+
+// sortedCounts returns the provided counts sorted by the number of findings, the number of suppressed findings and
+// then name.
+func sortedCounts(counts map[string]*reportCount) []reportCount {
+	var out []reportCount
+	for _, c := range counts {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Findings != out[j].Findings {
+			return out[i].Findings > out[j].Findings
+		}
+		if out[i].Suppressed != out[j].Suppressed {
+			return out[i].Suppressed > out[j].Suppressed
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>nobadfuncs report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+h1 { margin-bottom: 0.2em; }
+.summary { color: #57606a; margin-bottom: 2em; }
+.counts { display: flex; flex-wrap: wrap; gap: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #d0d7de; padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td.num { text-align: right; }
+code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 0.9em; }
+.finding { border: 1px solid #d0d7de; border-radius: 6px; margin-bottom: 1.5em; }
+.finding-header { background: #f6f8fa; border-bottom: 1px solid #d0d7de; padding: 0.5em 0.8em; }
+.finding-header .rule { background: #cf222e; border-radius: 1em; color: #fff; font-size: 0.8em; margin-left: 0.5em; padding: 0.1em 0.6em; }
+.finding-message { padding: 0.5em 0.8em; }
+.finding-meta { color: #57606a; font-size: 0.9em; padding: 0 0.8em 0.5em; }
+pre { margin: 0; overflow-x: auto; padding: 0.5em 0; }
+pre .line { display: block; padding: 0 0.8em; }
+pre .line.hl { background: #fff8c5; }
+pre .num { color: #8c959f; display: inline-block; margin-right: 1em; text-align: right; user-select: none; width: 4em; }
+pre mark { background: #ffb8b8; border-radius: 2px; }
+</style>
+</head>
+<body>
+<h1>nobadfuncs report</h1>
+<div class="summary">{{len .Findings}} finding{{if ne (len .Findings) 1}}s{{end}}, {{len .Suppressions}} suppressed</div>
+
+<div class="counts">
+<div>
+<h2>Signatures</h2>
+<table>
+<tr><th>Signature</th><th>Findings</th><th>Suppressed</th></tr>
+{{- range .Signatures}}
+<tr><td><code>{{.Name}}</code></td><td class="num">{{.Findings}}</td><td class="num">{{.Suppressed}}</td></tr>
+{{- end}}
+</table>
+</div>
+<div>
+<h2>Packages</h2>
+<table>
+<tr><th>Package</th><th>Findings</th><th>Suppressed</th></tr>
+{{- range .Packages}}
+<tr><td><code>{{.Name}}</code></td><td class="num">{{.Findings}}</td><td class="num">{{.Suppressed}}</td></tr>
+{{- end}}
+</table>
+</div>
+{{- if .Owners}}
+<div>
+<h2>Owners</h2>
+<table>
+<tr><th>Owner</th><th>Findings</th><th>Suppressed</th></tr>
+{{- range .Owners}}
+<tr><td>{{.Name}}</td><td class="num">{{.Findings}}</td><td class="num">{{.Suppressed}}</td></tr>
+{{- end}}
+</table>
+</div>
+{{- end}}
+</div>
+
+<h2>Findings</h2>
+{{- range .Findings}}
+<div class="finding">
+<div class="finding-header"><code>{{.Pos}}</code>{{if .RuleID}}<span class="rule">{{.RuleID}}</span>{{end}}</div>
+<div class="finding-message">{{.Message}}{{if .DocURL}} <a href="{{.DocURL}}">Documentation</a>{{end}}</div>
+{{- if or .Via .Owners}}
+<div class="finding-meta">{{if .Via}}Low confidence: referenced via {{.Via}}. {{end}}{{if .Owners}}Owners: {{range $i, $owner := .Owners}}{{if $i}}, {{end}}{{$owner}}{{end}}{{end}}</div>
+{{- end}}
+{{- if .Snippet}}
+<pre>{{range .Snippet}}<span class="line{{if .Match}} hl{{end}}"><span class="num">{{.Num}}</span>{{.Before}}{{if .Match}}<mark>{{.Match}}</mark>{{.After}}{{end}}</span>{{end}}</pre>
+{{- end}}
+</div>
+{{- else}}
+<p>No findings.</p>
+{{- end}}
+
+<h2>Suppressions</h2>
+{{- if .Suppressions}}
+<table>
+<tr><th>Position</th><th>Reference</th><th>Reason</th></tr>
+{{- range .Suppressions}}
+<tr><td><code>{{.Pos}}</code></td><td><code>{{.Ref}}</code></td><td>{{.Suppression}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No suppressed findings.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,117 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintBadRefsHTML(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	if len(os.Args) > 1 {
+		os.Exit(1)
+	}
+	// OK: <exit> is expected here
+	os.Exit(2)
+}
+`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src: `package bar
+
+import "os"
+
+func Bar() {
+	// OK: bar may exit
+	os.Exit(1)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	cfg := nobadfuncs.Config{
+		Deny: []nobadfuncs.Rule{
+			{
+				ID:      "no-exit",
+				Refs:    []string{"os.Exit"},
+				Message: "do not exit",
+			},
+		},
+	}
+
+	t.Run("suppressed findings", func(t *testing.T) {
+		findings, err := nobadfuncs.Check([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{IncludeSuppressed: true})
+		require.NoError(t, err)
+		var got []string
+		for _, finding := range findings {
+			got = append(got, finding.String())
+		}
+		assert.ElementsMatch(t, []string{
+			fmt.Sprintf("%s:7:5: do not exit (suppressed: bar may exit)", path.Join(projectDir, "bar/bar.go")),
+			fmt.Sprintf("%s:7:6: do not exit", path.Join(projectDir, "foo/foo.go")),
+			fmt.Sprintf("%s:10:5: do not exit (suppressed: <exit> is expected here)", path.Join(projectDir, "foo/foo.go")),
+		}, got)
+	})
+
+	t.Run("HTML report", func(t *testing.T) {
+		var got bytes.Buffer
+		err := nobadfuncs.PrintBadRefs([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{
+			Format: nobadfuncs.OutputFormatHTML,
+		}, &got)
+		require.Error(t, err)
+		out := got.String()
+
+		assert.Contains(t, out, `<div class="summary">1 finding, 2 suppressed</div>`)
+		assert.Contains(t, out, `<tr><td><code>func os.Exit(int)</code></td><td class="num">1</td><td class="num">2</td></tr>`)
+		assert.Contains(t, out, `<tr><td><code>github.com/palantir/go-nobadfuncs-test/foo</code></td><td class="num">1</td><td class="num">1</td></tr>`)
+		assert.Contains(t, out, `<tr><td><code>github.com/palantir/go-nobadfuncs-test/bar</code></td><td class="num">0</td><td class="num">1</td></tr>`)
+		assert.Contains(t, out, fmt.Sprintf(`<tr><td><code>%s:10:5</code></td><td><code>func os.Exit(int)</code></td><td>&lt;exit&gt; is expected here</td></tr>`, path.Join(projectDir, "foo/foo.go")))
+		assert.Contains(t, out, `<span class="line hl"><span class="num">7</span>		os.<mark>Exit</mark>(1)</span>`)
+		assert.Contains(t, out, `<span class="line"><span class="num">5</span>func Foo() {</span>`)
+		assert.NotContains(t, out, "<script")
+		assert.NotContains(t, out, `<link`)
+	})
+
+	t.Run("suppressed findings do not fail the check", func(t *testing.T) {
+		var got bytes.Buffer
+		err := nobadfuncs.PrintBadRefs([]string{"./bar"}, cfg, projectDir, nobadfuncs.Options{
+			Format: nobadfuncs.OutputFormatHTML,
+		}, &got)
+		require.NoError(t, err)
+		assert.Contains(t, got.String(), `<div class="summary">0 findings, 1 suppressed</div>`)
+	})
+}
@@ -0,0 +1,272 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"embed"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed presets/*.yml
+var presetFiles embed.FS
+
+// Presets returns the names of the built-in presets in sorted order.
+func Presets() []string {
+	entries, err := presetFiles.ReadDir("presets")
+	if err != nil {
+		panic(err)
+	}
+	var out []string
+	for _, entry := range entries {
+		out = append(out, strings.TrimSuffix(entry.Name(), ".yml"))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// presetAliases maps alternative names of built-in presets to their names.
+var presetAliases = map[string]string{
+	"unsafe-crypto": "crypto",
+}
+
+// loadPreset returns the configuration of the built-in preset with the provided name or alias. Presets may themselves
+// specify presets, but not includes.
+func loadPreset(name string) (Config, error) {
+	fileName := name
+	if alias, ok := presetAliases[name]; ok {
+		fileName = alias
+	}
+	presetBytes, err := presetFiles.ReadFile(path.Join("presets", fileName+".yml"))
+	if err != nil {
+		return Config{}, errors.Errorf("unknown preset %q: must be one of %s", name, strings.Join(Presets(), ", "))
+	}
+	cfg, err := ParseConfig(presetBytes)
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to parse preset %q", name)
+	}
+	if len(cfg.Include) > 0 {
+		return Config{}, errors.Errorf("preset %q may not specify includes", name)
+	}
+	return cfg, nil
+}
+
+// Resolve returns the configuration that results from merging the presets and included files of the configuration into
+// it and applying its overrides. Relative include and vulnerability database paths are resolved against dir. The
+// entries of the vulnerability database are added to the deny rules of the configuration that specifies it. The
+// returned configuration does not have any presets, includes, overrides or vulnerability database.
+//
+// The presets are merged in order, followed by the included files and then the configuration itself, so later
+// configurations take precedence: a rule or layer with the same ID (or name) as an inherited one replaces it, a reason
+// for a function signature replaces the inherited reason, a non-empty message suffix and an enabled deprecated
+// configuration replace the inherited ones and the match options are enabled if they are enabled in any configuration.
+func (c Config) Resolve(dir string) (Config, error) {
+	out, err := c.resolve(Config{}, dir, nil)
+	if err != nil {
+		return Config{}, err
+	}
+	out.path = c.path
+	return out, nil
+}
+
+// resolve returns the configuration that results from resolving the configuration on top of the provided resolved base
+// configuration, which takes the lowest precedence and to whose rules the overrides of the configuration also apply.
+// stack contains the presets and files that are being resolved and is used to detect cycles.
+func (c Config) resolve(base Config, dir string, stack []string) (Config, error) {
+	out := base
+	for _, name := range c.Presets {
+		if slices.Contains(stack, "preset:"+name) {
+			return Config{}, errors.Errorf("preset %q includes itself", name)
+		}
+		preset, err := loadPreset(name)
+		if err != nil {
+			return Config{}, err
+		}
+		if preset, err = preset.resolve(Config{}, dir, append(stack, "preset:"+name)); err != nil {
+			return Config{}, errors.Wrapf(err, "failed to resolve preset %q", name)
+		}
+		out = out.merge(preset)
+	}
+	for _, include := range c.Include {
+		includePath := include
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(dir, includePath)
+		}
+		if slices.Contains(stack, includePath) {
+			return Config{}, errors.Errorf("configuration file %s includes itself", includePath)
+		}
+		included, err := readConfig(includePath)
+		if err != nil {
+			return Config{}, err
+		}
+		if included, err = included.resolve(Config{}, filepath.Dir(includePath), append(stack, includePath)); err != nil {
+			return Config{}, errors.Wrapf(err, "failed to resolve configuration file %s", includePath)
+		}
+		out = out.merge(included)
+	}
+
+	local := c
+	local.Presets, local.Include, local.Overrides = nil, nil, nil
+	if local.VulnDB != "" {
+		vulnDBPath := local.VulnDB
+		if !filepath.IsAbs(vulnDBPath) {
+			vulnDBPath = filepath.Join(dir, vulnDBPath)
+		}
+		vulnRules, err := loadVulnDB(vulnDBPath)
+		if err != nil {
+			return Config{}, err
+		}
+		local.Deny = append(slices.Clip(local.Deny), vulnRules...)
+		local.VulnDB = ""
+	}
+	out = out.merge(local)
+
+	for _, override := range c.Overrides {
+		var err error
+		if out, err = out.applyOverride(override); err != nil {
+			return Config{}, err
+		}
+	}
+	return out, nil
+}
+
+// merge returns the configuration that results from merging the provided configuration into this one. The provided
+// configuration takes precedence.
+func (c Config) merge(other Config) Config {
+	out := Config{
+		Deprecated:    c.Deprecated,
+		MessageSuffix: c.MessageSuffix,
+		Match: MatchConfig{
+			PromotedMethods: c.Match.PromotedMethods || other.Match.PromotedMethods,
+			DefinedTypes:    c.Match.DefinedTypes || other.Match.DefinedTypes,
+			Aliases:         c.Match.Aliases || other.Match.Aliases,
+		},
+	}
+	if other.MessageSuffix != "" {
+		out.MessageSuffix = other.MessageSuffix
+	}
+	if other.Deprecated.Enabled {
+		out.Deprecated = other.Deprecated
+	}
+	if len(c.Funcs)+len(other.Funcs) > 0 {
+		out.Funcs = make(map[string]string)
+		for sig, reason := range c.Funcs {
+			out.Funcs[sig] = reason
+		}
+		for sig, reason := range other.Funcs {
+			out.Funcs[sig] = reason
+		}
+	}
+	out.Deny = mergeByID(c.Deny, other.Deny, func(r Rule) string { return r.ID })
+	out.Allow = mergeByID(c.Allow, other.Allow, func(r AllowRule) string { return r.ID })
+	out.Callers = mergeByID(c.Callers, other.Callers, func(r CallerRule) string { return r.ID })
+	out.Sequences = mergeByID(c.Sequences, other.Sequences, func(r SequenceRule) string { return r.ID })
+	out.Declarations = mergeByID(c.Declarations, other.Declarations, func(r DeclarationRule) string { return r.ID })
+	out.Constructs = mergeByID(c.Constructs, other.Constructs, func(r ConstructRule) string { return r.ID })
+	out.Fields = mergeByID(c.Fields, other.Fields, func(r FieldRule) string { return r.ID })
+	out.Layers = mergeByID(c.Layers, other.Layers, func(l Layer) string { return l.Name })
+	return out
+}
+
+// mergeByID returns the elements of inherited that do not have the same non-empty ID as an element of local followed by
+// the elements of local.
+func mergeByID[T any](inherited, local []T, id func(T) string) []T {
+	ids := make(map[string]struct{})
+	for _, curr := range local {
+		if currID := id(curr); currID != "" {
+			ids[currID] = struct{}{}
+		}
+	}
+	var out []T
+	for _, curr := range inherited {
+		if _, ok := ids[id(curr)]; !ok {
+			out = append(out, curr)
+		}
+	}
+	return append(out, local...)
+}
+
+// applyOverride returns the configuration that results from applying the provided override to the rules with its ID.
+// The ID "deprecated" refers to the deprecated configuration. Returns an error if no rule has the ID.
+func (c Config) applyOverride(override RuleOverride) (Config, error) {
+	if override.ID == "" {
+		return Config{}, errors.Errorf("override must specify the ID of a rule")
+	}
+	found := false
+	apply := func(packages *[]string, message *string) bool {
+		found = true
+		if override.Disabled {
+			return false
+		}
+		if override.Packages != nil {
+			*packages = override.Packages
+		}
+		if override.Message != "" {
+			*message = override.Message
+		}
+		return true
+	}
+
+	out := c
+	out.Deny, out.Allow, out.Callers, out.Sequences = nil, nil, nil, nil
+	out.Declarations, out.Constructs, out.Fields = nil, nil, nil
+	for _, rule := range c.Deny {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Deny = append(out.Deny, rule)
+		}
+	}
+	for _, rule := range c.Allow {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Allow = append(out.Allow, rule)
+		}
+	}
+	for _, rule := range c.Callers {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Callers = append(out.Callers, rule)
+		}
+	}
+	for _, rule := range c.Sequences {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Sequences = append(out.Sequences, rule)
+		}
+	}
+	for _, rule := range c.Declarations {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Declarations = append(out.Declarations, rule)
+		}
+	}
+	for _, rule := range c.Constructs {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Constructs = append(out.Constructs, rule)
+		}
+	}
+	for _, rule := range c.Fields {
+		if rule.ID != override.ID || apply(&rule.Packages, &rule.Message) {
+			out.Fields = append(out.Fields, rule)
+		}
+	}
+	if override.ID == deprecatedRuleID && out.Deprecated.Enabled && !apply(&out.Deprecated.Packages, &out.Deprecated.Message) {
+		out.Deprecated = DeprecatedConfig{}
+	}
+	if !found {
+		return Config{}, errors.Errorf("override for unknown rule %q", override.ID)
+	}
+	return out, nil
+}
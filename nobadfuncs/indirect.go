@@ -0,0 +1,292 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+const (
+	viaReflection = "reflection"
+	viaPlugin     = "plugin lookup"
+	viaLinkname   = "go:linkname"
+)
+
+// lookupFuncs are the qualified names of the functions that look up methods or symbols by name. The value is the
+// mechanism through which the looked up object is referenced.
+var lookupFuncs = map[string]string{
+	"(reflect.Value).MethodByName": viaReflection,
+	"(reflect.Type).MethodByName":  viaReflection,
+	"(*plugin.Plugin).Lookup":      viaPlugin,
+}
+
+// nameKind is the kind of object that is identified by a name that is passed to checkName.
+type nameKind int
+
+const (
+	// qualifiedName is the qualified name of an object (as in objRef.Name).
+	qualifiedName nameKind = iota
+	// methodName is the unqualified name of a method of a type that cannot be determined statically.
+	methodName
+	// memberName is the unqualified name of a package-level function or variable of a package that cannot be
+	// determined statically.
+	memberName
+)
+
+// lookupKinds are the kinds of the names that are looked up by the mechanisms of lookupFuncs when the receiver of the
+// lookup is not statically known.
+var lookupKinds = map[string]nameKind{
+	viaReflection: methodName,
+	viaPlugin:     memberName,
+}
+
+// checkIndirectRefs returns the findings for objects that are referenced in a manner that is not recorded in the type
+// information of the package: string constants passed to reflection and plugin lookup functions and the targets of
+// "//go:linkname" directives. Such findings are of lower confidence than direct references, so only function signatures
+// and deny rules are considered. The position of a lookup is the position of the identifier of the lookup function
+// (such as "MethodByName"), so deny rules that depend on the syntactic context or the parameters of the enclosing
+// function apply to it. Rules for discarded results do not apply, since the looked up object is not called.
+func (c *checker) checkIndirectRefs(pkg packageInfo, comments map[string]map[int]string, sites refSites) []Finding {
+	var findings []Finding
+	addFinding := func(pos ast.Node, finding Finding, via string) {
+		finding.Pos = pkg.Fset.Position(pos.Pos())
+		if isWhitelisted(comments, finding.Pos) {
+			return
+		}
+		finding.Via = via
+		findings = append(findings, finding)
+	}
+	sites.discarded = nil
+
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			lookupRef, ok := newObjRef(pkg.Info.Uses[sel.Sel])
+			if !ok {
+				return true
+			}
+			via, ok := lookupFuncs[lookupRef.Name]
+			if !ok {
+				return true
+			}
+			name, ok := stringConstant(pkg.Info, call.Args[0])
+			if !ok {
+				return true
+			}
+			pos := sel.Sel.Pos()
+			if via == viaReflection {
+				if typ := reflectedType(pkg.Info, sel.X); typ != nil {
+					// the receiver is statically known, so only its method can be referenced
+					if ref, ok := newObjRef(lookupMethod(typ, name)); ok {
+						if finding, ok := c.checkDenied(pkg, ref, sites, pos, c.refMessageData(pkg, ref, sites, pos)); ok {
+							addFinding(call.Args[0], finding, via)
+						}
+					}
+					return true
+				}
+			}
+			if finding, ok := c.checkName(pkg, name, "", lookupKinds[via], sites, pos); ok {
+				addFinding(call.Args[0], finding, via)
+			}
+			return true
+		})
+
+		for _, commentGroup := range file.Comments {
+			for _, comment := range commentGroup.List {
+				fields := strings.Fields(comment.Text)
+				if len(fields) != 3 || fields[0] != "//go:linkname" {
+					continue
+				}
+				name, pkgPath, ok := linknameTarget(fields[2])
+				if !ok {
+					continue
+				}
+				if finding, ok := c.checkName(pkg, name, pkgPath, qualifiedName, sites, comment.Pos()); ok {
+					addFinding(comment, finding, viaLinkname)
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// checkName returns the finding for a reference to an object that is known only by name, which is made at the provided
+// position. If kind is qualifiedName, pkgPath is the path of the package of the object. Otherwise, only rules that
+// match a method (for methodName) or a package-level function or variable (for memberName) with exactly the provided
+// name are considered.
+func (c *checker) checkName(pkg packageInfo, name, pkgPath string, kind nameKind, sites refSites, pos token.Pos) (Finding, bool) {
+	nameMatches := func(sig string) bool {
+		fullName := sigFullName(sig)
+		switch kind {
+		case qualifiedName:
+			return fullName == name
+		case methodName:
+			if !strings.HasPrefix(fullName, "(") {
+				return false
+			}
+		default:
+			if strings.HasPrefix(fullName, "(") {
+				return false
+			}
+		}
+		return fullName[strings.LastIndex(fullName, ".")+1:] == name
+	}
+	caller := sites.funcs.name(pos)
+
+	var sigs []string
+	for sig := range c.funcs {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		if !nameMatches(sig) {
+			continue
+		}
+		return Finding{
+			Ref:     sig,
+			Message: c.funcMessage(c.funcs[sig], MessageData{Signature: sig, Caller: caller, Package: removeVendor(pkg.Path)}),
+		}, true
+	}
+	for _, rule := range c.deny {
+		if rule.versions != nil || rule.When != "" || !rule.pkgs.matches(pkg.Path, pkg.ModulePath) {
+			// the module of an object that is known only by name cannot be determined and the object is not called
+			continue
+		}
+		if rule.EnclosingParam != "" && !sites.params.has(pos, rule.EnclosingParam) {
+			continue
+		}
+		if rule.contexts != 0 && rule.contexts&sites.contexts[pos] == 0 {
+			continue
+		}
+		for _, pattern := range rule.refs {
+			var matches bool
+			switch {
+			case pattern.exact:
+				matches = nameMatches(pattern.raw)
+			case kind == qualifiedName:
+				matches = pattern.matches(objRef{Name: name, PkgPath: pkgPath})
+			default:
+				// only patterns without wildcards identify a single function name
+				matches = !strings.Contains(strings.ReplaceAll(pattern.raw, "(*", "("), "*") && !strings.Contains(pattern.raw, "...") && nameMatches(pattern.raw)
+			}
+			if !matches {
+				continue
+			}
+			data := MessageData{Signature: name, Caller: caller, Package: removeVendor(pkg.Path)}
+			return c.ruleFinding(name, rule.ID, rule.Replacement, rule.DocURL, rule.msg, defaultDenyMessage(name), data), true
+		}
+	}
+	return Finding{}, false
+}
+
+// stringConstant returns the value of the provided expression if it is a constant string.
+func stringConstant(info *types.Info, expr ast.Expr) (string, bool) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// reflectedType returns the static type of the value passed to "reflect.ValueOf" or "reflect.TypeOf" in the provided
+// expression. Returns nil if the expression is not such a call or if the type is an interface, in which case the type
+// of the reflected value cannot be determined statically.
+func reflectedType(info *types.Info, expr ast.Expr) types.Type {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	ref, ok := newObjRef(info.Uses[sel.Sel])
+	if !ok || (ref.Name != "reflect.ValueOf" && ref.Name != "reflect.TypeOf") {
+		return nil
+	}
+	typ := info.TypeOf(call.Args[0])
+	if typ == nil || types.IsInterface(typ) {
+		return nil
+	}
+	return typ
+}
+
+// lookupMethod returns the method with the provided name in the method set of the provided type. Returns nil if there is
+// no such method.
+func lookupMethod(typ types.Type, name string) types.Object {
+	obj, _, _ := types.LookupFieldOrMethod(typ, true, nil, name)
+	if method, ok := obj.(*types.Func); ok {
+		return method
+	}
+	return nil
+}
+
+// linknameTarget returns the qualified name (as in objRef.Name) and package path of the target of a "//go:linkname"
+// directive, which is of the form "path/to/pkg.name", "path/to/pkg.T.method" or "path/to/pkg.(*T).method".
+func linknameTarget(target string) (string, string, bool) {
+	slashIdx := strings.LastIndex(target, "/")
+	dotIdx := strings.Index(target[slashIdx+1:], ".")
+	if dotIdx == -1 {
+		return "", "", false
+	}
+	pkgPath := target[:slashIdx+1+dotIdx]
+	name := target[slashIdx+1+dotIdx+1:]
+	if name == "" {
+		return "", "", false
+	}
+	if strings.HasPrefix(name, "(*") {
+		// pointer receiver: "(*T).method"
+		typeAndMethod := strings.SplitN(strings.TrimPrefix(name, "(*"), ").", 2)
+		if len(typeAndMethod) != 2 {
+			return "", "", false
+		}
+		return fmt.Sprintf("(*%s.%s).%s", pkgPath, typeAndMethod[0], typeAndMethod[1]), pkgPath, true
+	}
+	if typeAndMethod := strings.SplitN(name, ".", 2); len(typeAndMethod) == 2 {
+		return fmt.Sprintf("(%s.%s).%s", pkgPath, typeAndMethod[0], typeAndMethod[1]), pkgPath, true
+	}
+	return pkgPath + "." + name, pkgPath, true
+}
+
+// sigFullName returns the qualified name of the function with the provided FuncRef. For example, the full name of
+// "func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)" is "(*net/http.Client).Do". If the
+// provided string is not a FuncRef, it is returned unmodified.
+func sigFullName(sig string) string {
+	name := strings.TrimPrefix(sig, "func ")
+	start := 0
+	if strings.HasPrefix(name, "(") {
+		// skip receiver
+		if end := strings.Index(name, ")"); end != -1 {
+			start = end
+		}
+	}
+	if paramsIdx := strings.Index(name[start:], "("); paramsIdx != -1 {
+		name = name[:start+paramsIdx]
+	}
+	return name
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deprecation determines the "Deprecated:" paragraphs of Go documentation. It is used both to report references
+// to deprecated objects and to generate the "deprecated-stdlib" preset, so that both agree on what is deprecated.
+package deprecation
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Text returns the text of the "Deprecated:" paragraph of the provided documentation with its whitespace normalized.
+// Returns false if the documentation does not have such a paragraph.
+func Text(doc *ast.CommentGroup) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(paragraph, "Deprecated:") {
+			return strings.Join(strings.Fields(strings.TrimPrefix(paragraph, "Deprecated:")), " "), true
+		}
+	}
+	return "", false
+}
+
+// VisitSpecs calls the provided function for each name declared by the provided declaration with the documentation that
+// applies to it, which is the documentation of its spec or, if the spec does not have any, of the declaration. The
+// documentation of a group of values does not apply to the individual values in the group. The documentation is nil if
+// none applies. Import declarations do not declare any names.
+func VisitSpecs(decl *ast.GenDecl, fn func(name *ast.Ident, doc *ast.CommentGroup)) {
+	if decl.Tok == token.IMPORT {
+		return
+	}
+	for _, spec := range decl.Specs {
+		doc := decl.Doc
+		var names []*ast.Ident
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			if spec.Doc != nil {
+				doc = spec.Doc
+			}
+			names = []*ast.Ident{spec.Name}
+		case *ast.ValueSpec:
+			if spec.Doc != nil {
+				doc = spec.Doc
+			} else if len(decl.Specs) > 1 {
+				// the documentation of a group does not apply to the individual values in the group
+				doc = nil
+			}
+			names = spec.Names
+		}
+		for _, name := range names {
+			fn(name, doc)
+		}
+	}
+}
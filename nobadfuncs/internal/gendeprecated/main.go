@@ -0,0 +1,158 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gendeprecated generates the "deprecated-stdlib" preset from the "Deprecated:" doc comments of the exported APIs of the
+// standard library of the Go toolchain that runs it.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs/internal/deprecation"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	out := flag.String("out", "", "path to the output file")
+	flag.Parse()
+	if err := run(*out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out string) error {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax,
+	}, "std")
+	if err != nil {
+		return errors.Wrapf(err, "failed to load standard library")
+	}
+
+	deprecated := make(map[string]string)
+	for _, pkg := range pkgs {
+		if isInternal(pkg.PkgPath) {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			if msg, ok := deprecation.Text(file.Doc); ok {
+				deprecated[pkg.PkgPath] = msg
+			}
+			for _, decl := range file.Decls {
+				addDecl(deprecated, pkg.PkgPath, decl)
+			}
+		}
+	}
+
+	var refs []string
+	for ref := range deprecated {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		// rules for deprecated packages are last so that the more specific messages of their members take precedence
+		if iPkg, jPkg := !strings.Contains(refs[i], "."), !strings.Contains(refs[j], "."); iPkg != jPkg {
+			return jPkg
+		}
+		return refs[i] < refs[j]
+	})
+
+	var cfg nobadfuncs.Config
+	for _, ref := range refs {
+		cfg.Deny = append(cfg.Deny, nobadfuncs.Rule{
+			ID:          "deprecated-stdlib",
+			Refs:        []string{ref},
+			Message:     "{{.Signature}} is deprecated: {{.Replacement}} {{.Suffix}}",
+			Replacement: deprecated[ref],
+		})
+	}
+
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "# Code generated by gendeprecated from the %s standard library. DO NOT EDIT.\n", runtime.Version())
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := enc.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.WriteFile(out, buf.Bytes(), 0644))
+}
+
+func isInternal(pkgPath string) bool {
+	for _, part := range strings.Split(pkgPath, "/") {
+		if part == "internal" || part == "vendor" {
+			return true
+		}
+	}
+	return strings.HasPrefix(pkgPath, "cmd/")
+}
+
+// addDecl records the exported objects declared by the provided declaration that are deprecated.
+func addDecl(deprecated map[string]string, pkgPath string, decl ast.Decl) {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if !decl.Name.IsExported() {
+			return
+		}
+		msg, ok := deprecation.Text(decl.Doc)
+		if !ok {
+			return
+		}
+		if decl.Recv == nil {
+			deprecated[pkgPath+"."+decl.Name.Name] = msg
+			return
+		}
+		recv := decl.Recv.List[0].Type
+		format := "(%s.%s).%s"
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+			format = "(*%s.%s).%s"
+		}
+		switch index := recv.(type) {
+		case *ast.IndexExpr:
+			recv = index.X
+		case *ast.IndexListExpr:
+			recv = index.X
+		}
+		recvIdent, ok := recv.(*ast.Ident)
+		if !ok || !recvIdent.IsExported() {
+			return
+		}
+		deprecated[fmt.Sprintf(format, pkgPath, recvIdent.Name, decl.Name.Name)] = msg
+	case *ast.GenDecl:
+		deprecation.VisitSpecs(decl, func(name *ast.Ident, doc *ast.CommentGroup) {
+			if !name.IsExported() {
+				return
+			}
+			if msg, ok := deprecation.Text(doc); ok {
+				deprecated[pkgPath+"."+name.Name] = msg
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// InventoryFormat is the output format of an API inventory report.
+type InventoryFormat string
+
+const (
+	InventoryFormatJSON     InventoryFormat = "json"
+	InventoryFormatMarkdown InventoryFormat = "markdown"
+)
+
+const (
+	// stdModulePath is the module path used to group references to the packages of the standard library.
+	stdModulePath = "std"
+	// noModulePath is the module path used to group references to packages that are not part of any module and are
+	// not in the standard library, such as packages in GOPATH.
+	noModulePath = "(no module)"
+)
+
+// ModuleInventory is the set of symbols of a single module that are referenced by the checked packages.
+type ModuleInventory struct {
+	Path       string        `json:"path"`
+	Version    string        `json:"version,omitempty"`
+	References int           `json:"references"`
+	Symbols    []SymbolUsage `json:"symbols"`
+}
+
+// SymbolUsage records the references to a single symbol. Symbol is the string representation of the referenced object
+// with vendor directories removed: function and method symbols are of the same form as FuncRef.
+type SymbolUsage struct {
+	Kind     string `json:"kind"`
+	Symbol   string `json:"symbol"`
+	Count    int    `json:"count"`
+	FirstUse string `json:"firstUse"`
+}
+
+// PrintAPIInventory prints a report of all of the functions, methods, types and variables defined outside of the main
+// module that are referenced by the provided packages, grouped by module. References to the standard library are only
+// included if includeStd is true.
+func PrintAPIInventory(pkgs []string, dir string, format InventoryFormat, includeStd bool, w io.Writer) error {
+	// the format is validated before the packages are loaded
+	if format != InventoryFormatJSON && format != InventoryFormatMarkdown {
+		return errors.Errorf("unsupported inventory format: %q", format)
+	}
+	inventory, err := APIInventory(pkgs, dir, includeStd)
+	if err != nil {
+		return err
+	}
+	if format == InventoryFormatMarkdown {
+		printInventoryMarkdown(inventory, w)
+		return nil
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inventory)
+}
+
+// APIInventory returns the inventory of the external symbols referenced by the provided packages. The returned modules
+// are sorted by path and the symbols of each module are sorted by symbol.
+func APIInventory(pkgs []string, dir string, includeStd bool) ([]ModuleInventory, error) {
+	loadedPkgs, err := loadPackages(pkgs, dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// map from package path to the module that provides it for the checked packages and all of their dependencies
+	pkgModules := make(map[string]*packages.Module)
+	packages.Visit(loadedPkgs, nil, func(pkg *packages.Package) {
+		pkgModules[removeVendor(pkg.PkgPath)] = pkg.Module
+	})
+	// the checked packages, whose references to each other are not external if they are not in a module
+	checkedPkgs := make(map[string]struct{})
+	for _, loadedPkg := range loadedPkgs {
+		checkedPkgs[removeVendor(loadedPkg.PkgPath)] = struct{}{}
+	}
+
+	type symbolKey struct {
+		module string
+		symbol string
+	}
+	modules := make(map[string]*ModuleInventory)
+	usages := make(map[symbolKey]*SymbolUsage)
+	firstUses := make(map[symbolKey]token.Position)
+
+	for _, loadedPkg := range loadedPkgs {
+		var keys []*ast.Ident
+		for k := range loadedPkg.TypesInfo.Uses {
+			keys = append(keys, k)
+		}
+		sort.Sort(identSlice(keys))
+
+		for _, id := range keys {
+			obj := loadedPkg.TypesInfo.Uses[id]
+			kind, symbol, ok := inventorySymbol(obj)
+			if !ok {
+				continue
+			}
+
+			objPkgPath := removeVendor(obj.Pkg().Path())
+			mod := pkgModules[objPkgPath]
+			if mod != nil && mod.Main {
+				continue
+			}
+			var modPath, modVersion string
+			switch {
+			case mod != nil:
+				modPath, modVersion = mod.Path, mod.Version
+				if mod.Replace != nil && mod.Replace.Version != "" {
+					modVersion = mod.Replace.Version
+				}
+			case isStdlib(objPkgPath):
+				if !includeStd {
+					continue
+				}
+				modPath = stdModulePath
+			default:
+				if _, ok := checkedPkgs[objPkgPath]; ok {
+					continue
+				}
+				modPath = noModulePath
+			}
+
+			modInventory := modules[modPath]
+			if modInventory == nil {
+				modInventory = &ModuleInventory{
+					Path:    modPath,
+					Version: modVersion,
+				}
+				modules[modPath] = modInventory
+			}
+			modInventory.References++
+
+			pos := loadedPkg.Fset.Position(id.Pos())
+			key := symbolKey{module: modPath, symbol: symbol}
+			usage := usages[key]
+			if usage == nil {
+				usage = &SymbolUsage{
+					Kind:   kind,
+					Symbol: symbol,
+				}
+				usages[key] = usage
+			}
+			usage.Count++
+			if firstUse, ok := firstUses[key]; !ok || posLess(pos, firstUse) {
+				firstUses[key] = pos
+				usage.FirstUse = pos.String()
+			}
+		}
+	}
+
+	for key, usage := range usages {
+		modules[key.module].Symbols = append(modules[key.module].Symbols, *usage)
+	}
+	var out []ModuleInventory
+	for _, mod := range modules {
+		sort.Slice(mod.Symbols, func(i, j int) bool {
+			return mod.Symbols[i].Symbol < mod.Symbols[j].Symbol
+		})
+		out = append(out, *mod)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Path < out[j].Path
+	})
+	return out, nil
+}
+
+// isStdlib returns true if the package with the provided path, which is not part of any module, is in the standard
+// library. Import paths whose first element does not contain a dot are reserved for the standard library, except for
+// the path of the package that the go command synthesizes for files listed on the command line.
+func isStdlib(pkgPath string) bool {
+	first, _, _ := strings.Cut(pkgPath, "/")
+	return !strings.Contains(first, ".") && pkgPath != "command-line-arguments"
+}
+
+// inventorySymbol returns the kind and string representation of the provided object. Returns false if the object is
+// not a function, method, type or package-level variable.
+func inventorySymbol(obj types.Object) (string, string, bool) {
+	ref, ok := newObjRef(obj)
+	if !ok {
+		return "", "", false
+	}
+	switch o := obj.(type) {
+	case *types.Func:
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return "method", ref.Sig, true
+		}
+		return "func", ref.Sig, true
+	case *types.TypeName:
+		return "type", ref.Sig, true
+	case *types.Var:
+		return "var", ref.Sig, true
+	default:
+		return "", "", false
+	}
+}
+
+// qualifiedNameRemoveVendor returns the name of the provided package-level object qualified by the path of its package
+// with any vendor directory removed.
+func qualifiedNameRemoveVendor(obj types.Object) string {
+	return removeVendor(obj.Pkg().Path()) + "." + obj.Name()
+}
+
+func posLess(a, b token.Position) bool {
+	if a.Filename != b.Filename {
+		return a.Filename < b.Filename
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
+func printInventoryMarkdown(inventory []ModuleInventory, w io.Writer) {
+	_, _ = fmt.Fprintln(w, "# API inventory")
+	for _, mod := range inventory {
+		heading := mod.Path
+		if mod.Version != "" {
+			heading += " " + mod.Version
+		}
+		_, _ = fmt.Fprintf(w, "\n## %s\n\n", heading)
+		_, _ = fmt.Fprintf(w, "%d references to %d symbols.\n\n", mod.References, len(mod.Symbols))
+		_, _ = fmt.Fprintln(w, "| Kind | Symbol | Uses | First use |")
+		_, _ = fmt.Fprintln(w, "| --- | --- | --- | --- |")
+		for _, symbol := range mod.Symbols {
+			_, _ = fmt.Fprintf(w, "| %s | `%s` | %d | %s |\n", symbol.Kind, strings.ReplaceAll(symbol.Symbol, "|", `\|`), symbol.Count, symbol.FirstUse)
+		}
+	}
+}
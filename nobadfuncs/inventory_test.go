@@ -0,0 +1,193 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintAPIInventory(t *testing.T) {
+	// dependency is provided using a local "replace" directive, which does not require network access.
+	prevValue := os.Getenv("GOFLAGS")
+	defer func() {
+		_ = os.Setenv("GOFLAGS", prevValue)
+	}()
+	err := os.Setenv("GOFLAGS", "-mod=mod")
+	require.NoError(t, err)
+
+	projectDir := t.TempDir()
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src: `module github.com/palantir/go-nobadfuncs-test
+
+require github.com/bar v1.2.3
+
+replace github.com/bar => ./bar
+`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `
+package foo
+
+import (
+	"fmt"
+
+	"github.com/bar"
+	"github.com/palantir/go-nobadfuncs-test/baz"
+)
+
+func MyFunction() {
+	var b bar.BarType
+	b.Bar(bar.BarType(""))
+	bar.FreeBar()
+	bar.FreeBar()
+	fmt.Println(bar.BarVar, baz.Baz)
+}
+`,
+		},
+		{
+			RelPath: "baz/baz.go",
+			Src: `
+package baz
+
+var Baz = 1
+`,
+		},
+		{
+			RelPath: "bar/go.mod",
+			Src:     `module github.com/bar`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src: `
+package bar
+
+type BarType string
+
+var BarVar BarType
+
+func FreeBar() {}
+
+func (b BarType) Bar(in BarType) BarType {
+	return in
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	fooFile := path.Join(projectDir, "foo/foo.go")
+
+	t.Run("markdown", func(t *testing.T) {
+		var got bytes.Buffer
+		err := nobadfuncs.PrintAPIInventory([]string{"./foo"}, projectDir, nobadfuncs.InventoryFormatMarkdown, false, &got)
+		require.NoError(t, err)
+
+		want := fmt.Sprintf(`# API inventory
+
+## github.com/bar v1.2.3
+
+6 references to 4 symbols.
+
+| Kind | Symbol | Uses | First use |
+| --- | --- | --- | --- |
+| method | `+"`func (github.com/bar.BarType).Bar(github.com/bar.BarType) github.com/bar.BarType`"+` | 1 | %s:13:4 |
+| func | `+"`func github.com/bar.FreeBar()`"+` | 2 | %s:14:6 |
+| type | `+"`type github.com/bar.BarType`"+` | 2 | %s:12:12 |
+| var | `+"`var github.com/bar.BarVar`"+` | 1 | %s:16:18 |
+`, fooFile, fooFile, fooFile, fooFile)
+		assert.Equal(t, want, got.String())
+	})
+
+	t.Run("include standard library", func(t *testing.T) {
+		inventory, err := nobadfuncs.APIInventory([]string{"./foo"}, projectDir, true)
+		require.NoError(t, err)
+
+		require.Len(t, inventory, 2)
+		assert.Equal(t, "std", inventory[1].Path)
+		assert.Equal(t, []nobadfuncs.SymbolUsage{
+			{
+				Kind:     "func",
+				Symbol:   "func fmt.Println(...any) (int, error)",
+				Count:    1,
+				FirstUse: fmt.Sprintf("%s:16:6", fooFile),
+			},
+		}, inventory[1].Symbols)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		// the format is validated before the packages are loaded
+		err := nobadfuncs.PrintAPIInventory([]string{"./foo"}, path.Join(projectDir, "missing"), "xml", false, &bytes.Buffer{})
+		assert.EqualError(t, err, `unsupported inventory format: "xml"`)
+	})
+
+	t.Run("packages outside of modules", func(t *testing.T) {
+		gopath := t.TempDir()
+		for relPath, src := range map[string]string{
+			"src/example.com/app/app.go": `package app
+
+import (
+	"fmt"
+
+	"example.com/lib"
+)
+
+func App() {
+	fmt.Println(lib.Lib())
+	Helper()
+}
+
+func Helper() {}
+`,
+			"src/example.com/lib/lib.go": `package lib
+
+func Lib() string {
+	return ""
+}
+`,
+		} {
+			// gofiles.Write requires a module
+			require.NoError(t, os.MkdirAll(path.Dir(path.Join(gopath, relPath)), 0755))
+			require.NoError(t, os.WriteFile(path.Join(gopath, relPath), []byte(src), 0644))
+		}
+		t.Setenv("GOPATH", gopath)
+		t.Setenv("GO111MODULE", "off")
+		t.Setenv("GOFLAGS", "")
+
+		inventory, err := nobadfuncs.APIInventory([]string{"example.com/app"}, gopath, true)
+		require.NoError(t, err)
+		var got []string
+		for _, mod := range inventory {
+			for _, symbol := range mod.Symbols {
+				got = append(got, mod.Path+": "+symbol.Symbol)
+			}
+		}
+		assert.Equal(t, []string{
+			"(no module): func example.com/lib.Lib() string",
+			"std: func fmt.Println(...any) (int, error)",
+		}, got)
+	})
+}
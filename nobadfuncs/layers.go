@@ -0,0 +1,103 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+type layerMatcher struct {
+	Layer
+	pkgs  pkgPatterns
+	allow map[string]struct{}
+}
+
+type layerMatchers []layerMatcher
+
+func newLayerMatchers(layers []Layer) (layerMatchers, error) {
+	names := make(map[string]struct{})
+	for _, layer := range layers {
+		if layer.Name == "" {
+			return nil, errors.Errorf("layer name must be non-empty")
+		}
+		if _, ok := names[layer.Name]; ok {
+			return nil, errors.Errorf("layer %q is defined more than once", layer.Name)
+		}
+		names[layer.Name] = struct{}{}
+	}
+
+	var out layerMatchers
+	for _, layer := range layers {
+		pkgs, err := newPkgPatterns(layer.Packages)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid layer %q", layer.Name)
+		}
+		if len(pkgs) == 0 {
+			return nil, errors.Errorf("layer %q must specify at least one package pattern", layer.Name)
+		}
+		allow := make(map[string]struct{})
+		for _, allowed := range layer.Allow {
+			if _, ok := names[allowed]; !ok {
+				return nil, errors.Errorf("layer %q allows unknown layer %q", layer.Name, allowed)
+			}
+			allow[allowed] = struct{}{}
+		}
+		out = append(out, layerMatcher{
+			Layer: layer,
+			pkgs:  pkgs,
+			allow: allow,
+		})
+	}
+	return out, nil
+}
+
+// layerFor returns the layer to which the provided package belongs. Returns nil if the package does not belong to any
+// layer.
+func (l layerMatchers) layerFor(pkgPath, modulePath string) *layerMatcher {
+	for i := range l {
+		if l[i].pkgs.matches(pkgPath, modulePath) {
+			return &l[i]
+		}
+	}
+	return nil
+}
+
+// check returns the message (without a suffix) for the provided reference if it crosses layers in a manner that is not
+// allowed. Returns false if the reference is allowed.
+func (l layerMatchers) check(pkg packageInfo, ref objRef) (string, bool) {
+	if len(l) == 0 {
+		return "", false
+	}
+	toLayer := l.layerFor(ref.PkgPath, pkg.ModulePath)
+	if toLayer == nil {
+		return "", false
+	}
+	fromLayer := l.layerFor(removeVendor(pkg.Path), pkg.ModulePath)
+	if fromLayer == nil {
+		if !toLayer.Restricted {
+			return "", false
+		}
+		return fmt.Sprintf("references to %q in layer %q are only allowed from layers that allow it.", ref.Sig, toLayer.Name), true
+	}
+	if fromLayer.Name == toLayer.Name {
+		return "", false
+	}
+	if _, ok := fromLayer.allow[toLayer.Name]; ok {
+		return "", false
+	}
+	return fmt.Sprintf("references to %q in layer %q are not allowed from layer %q.", ref.Sig, toLayer.Name, fromLayer.Name), true
+}
@@ -0,0 +1,230 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/types"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// objRef is a reference to a package-level object (or a method).
+type objRef struct {
+	// Obj is the referenced object.
+	Obj types.Object
+	// Sig is the string representation of the referenced object with identifier names and vendor directories removed.
+	// For functions and methods, this is the FuncRef. For other objects, it is of the form "type net/http.Client" or
+	// "var net/http.DefaultClient".
+	Sig string
+	// Name is the qualified name of the referenced object with vendor directories removed. It is of the form
+	// "net/http.Get", "(*net/http.Client).Do" or "net/http.Client".
+	Name string
+	// PkgPath is the path of the package that defines the referenced object with vendor directories removed.
+	PkgPath string
+	// Alts are the equivalent forms of the reference that are also matched against function signatures and deny rules:
+	// the instantiated form of a generic function or method and the forms configured by MatchConfig.
+	Alts []objRef
+}
+
+// sigs returns the Sig of the reference followed by the Sig of all of its equivalent forms.
+func (r objRef) sigs() []string {
+	out := []string{r.Sig}
+	for _, alt := range r.Alts {
+		out = append(out, alt.Sig)
+	}
+	return out
+}
+
+// newObjRef returns the objRef for the provided object. Returns false if the object is not a function, method, type,
+// constant or package-level variable defined in a package.
+func newObjRef(obj types.Object) (objRef, bool) {
+	if obj == nil || obj.Pkg() == nil {
+		return objRef{}, false
+	}
+	out := objRef{
+		Obj:     obj,
+		PkgPath: removeVendor(obj.Pkg().Path()),
+	}
+	switch o := obj.(type) {
+	case *types.Func:
+		funcPtr := toFuncWithNoIdentifiersRemoveVendor(o.Origin())
+		out.Sig = funcPtr.String()
+		out.Name = funcPtr.FullName()
+		if o.Origin() != o {
+			// the signature of an instantiated function or method (which is the form printed by PrintAllFuncRefs)
+			// also matches
+			instFuncPtr := toFuncWithNoIdentifiersRemoveVendor(o)
+			if instSig := instFuncPtr.String(); instSig != out.Sig {
+				out.Alts = append(out.Alts, objRef{
+					Obj:     o,
+					PkgPath: out.PkgPath,
+					Sig:     instSig,
+					Name:    instFuncPtr.FullName(),
+				})
+			}
+		}
+	case *types.TypeName:
+		out.Name = qualifiedNameRemoveVendor(o)
+		out.Sig = "type " + out.Name
+	case *types.Const:
+		out.Name = qualifiedNameRemoveVendor(o)
+		out.Sig = "const " + out.Name
+	case *types.Var:
+		if o.IsField() || o.Parent() != o.Pkg().Scope() {
+			return objRef{}, false
+		}
+		out.Name = qualifiedNameRemoveVendor(o)
+		out.Sig = "var " + out.Name
+	default:
+		return objRef{}, false
+	}
+	return out, true
+}
+
+// refPattern matches references to objects. A pattern that begins with "func " must match the FuncRef of a function
+// exactly. Otherwise, the pattern is matched against both the qualified name of the referenced object (for example,
+// "os.Getenv" or "(*net/http.Client).Do") and the path of the package that defines it (for example, "net/http"). In
+// such patterns, "..." matches any string and "*" matches any string that does not contain a "/", except that a "*"
+// directly following a "(" matches a literal "*" so that pointer receivers can be specified.
+type refPattern struct {
+	raw   string
+	exact bool
+	re    *regexp.Regexp
+}
+
+func newRefPattern(pattern string) (refPattern, error) {
+	if pattern == "" {
+		return refPattern{}, errors.Errorf("pattern must be non-empty")
+	}
+	if strings.HasPrefix(pattern, "func ") {
+		return refPattern{
+			raw:   pattern,
+			exact: true,
+		}, nil
+	}
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return refPattern{}, err
+	}
+	return refPattern{
+		raw: pattern,
+		re:  re,
+	}, nil
+}
+
+func (p refPattern) matches(ref objRef) bool {
+	if p.exact {
+		if ref.Sig == p.raw {
+			return true
+		}
+	} else if p.re.MatchString(ref.Name) || p.re.MatchString(ref.PkgPath) {
+		return true
+	}
+	for _, alt := range ref.Alts {
+		if p.matches(alt) {
+			return true
+		}
+	}
+	return false
+}
+
+type refPatterns []refPattern
+
+func newRefPatterns(patterns []string) (refPatterns, error) {
+	var out refPatterns
+	for _, pattern := range patterns {
+		p, err := newRefPattern(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid reference pattern %q", pattern)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (p refPatterns) matches(ref objRef) bool {
+	for _, currPattern := range p {
+		if currPattern.matches(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// pkgPatterns matches package import paths. Patterns use the same syntax as the patterns for "go list": "..." matches
+// any string and a trailing "/..." also matches the empty string, so "foo/..." matches both "foo" and "foo/bar". A
+// pattern matches a package if it matches the full import path of the package or the import path relative to the module
+// that contains the package, so "internal/..." matches all of the "internal" packages of the main module. A leading
+// "./" in a pattern is ignored.
+type pkgPatterns []*regexp.Regexp
+
+func newPkgPatterns(patterns []string) (pkgPatterns, error) {
+	var out pkgPatterns
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "./")
+		if pattern == "" {
+			return nil, errors.Errorf("package pattern must be non-empty")
+		}
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid package pattern %q", pattern)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// matches returns true if any of the patterns match the provided package. If there are no patterns, all packages match.
+func (p pkgPatterns) matches(pkgPath, modulePath string) bool {
+	if len(p) == 0 {
+		return true
+	}
+	relPath := ""
+	if modulePath != "" && strings.HasPrefix(pkgPath, modulePath+"/") {
+		relPath = strings.TrimPrefix(pkgPath, modulePath+"/")
+	} else if pkgPath == modulePath {
+		relPath = "."
+	}
+	for _, re := range p {
+		if re.MatchString(pkgPath) || (relPath != "" && re.MatchString(relPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp returns the regular expression that matches the provided glob pattern in its entirety.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "/...") && i+len("/...") == len(pattern):
+			sb.WriteString("(/.*)?")
+			i += len("/...") - 1
+		case strings.HasPrefix(pattern[i:], "..."):
+			sb.WriteString(".*")
+			i += len("...") - 1
+		case pattern[i] == '*' && (i == 0 || pattern[i-1] != '('):
+			sb.WriteString("[^/]*")
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	whitelistHint            = "Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it."
+	declarationWhitelistHint = "Remove this declaration or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it."
+)
+
+// MessageData is the data that is available to message templates. Messages are Go text templates, so a message of the
+// form "{{.Signature}} is deprecated: use {{.Replacement}} instead (see {{.DocURL}})" interpolates the fields of the
+// rule and the finding.
+type MessageData struct {
+	// ID is the ID of the rule that was violated.
+	ID string
+	// Signature is the string representation of the referenced or declared object. For functions and methods, this is
+	// the FuncRef.
+	Signature string
+	// Caller is the qualified name of the function or method that contains the reference (for example,
+	// "github.com/org/repo/foo.MyFunction" or "(*github.com/org/repo/foo.Client).Do"). Empty if the reference is not in
+	// a function.
+	Caller string
+	// Package is the import path of the package that contains the reference.
+	Package string
+	// Module is the path of the module that contains the referenced object. Empty if the object is not in a module
+	// (for example, if it is in the standard library).
+	Module string
+	// Version is the version of the module that contains the referenced object. Empty if the object is not in a module
+	// or if the module is the main module.
+	Version string
+	// Replacement is the replacement specified by the rule.
+	Replacement string
+	// DocURL is the documentation URL specified by the rule.
+	DocURL string
+	// Suffix is the rendered message suffix. By default, this is the hint that describes how to whitelist a finding,
+	// which is appended to the default messages.
+	Suffix string
+}
+
+// newMessageTemplate parses the provided message template. Returns nil if the message is empty.
+func newMessageTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid message template %q", text)
+	}
+	// verify that the template only uses fields that exist
+	if err := tmpl.Execute(io.Discard, MessageData{}); err != nil {
+		return nil, errors.Wrapf(err, "invalid message template %q", text)
+	}
+	return tmpl, nil
+}
+
+// render returns the message for a finding. If tmpl is nil, the message is defaultPrefix followed by the rendered
+// suffix, which is defaultSuffix if the checker does not have a configured suffix template.
+func (c *checker) render(tmpl *template.Template, defaultPrefix, defaultSuffix string, data MessageData) string {
+	data.Suffix = defaultSuffix
+	if c.suffix != nil {
+		data.Suffix = executeTemplate(c.suffix, data)
+	}
+	if tmpl == nil {
+		if data.Suffix == "" {
+			return defaultPrefix
+		}
+		return defaultPrefix + " " + data.Suffix
+	}
+	return executeTemplate(tmpl, data)
+}
+
+// funcMessage returns the message for a reference to a function signature of Config.Funcs with the provided reason.
+// For compatibility, reasons are used verbatim rather than as templates. If the reason is empty, the default message is
+// used.
+func (c *checker) funcMessage(reason string, data MessageData) string {
+	if reason != "" {
+		return reason
+	}
+	return c.render(nil, defaultDenyMessage(data.Signature), whitelistHint, data)
+}
+
+func executeTemplate(tmpl *template.Template, data MessageData) string {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return tmpl.Root.String()
+	}
+	return sb.String()
+}
+
+// enclosingFuncs records the function declarations of a package so that the function that contains a position can be
+// determined.
+type enclosingFuncs struct {
+	decls []*ast.FuncDecl
+	info  *types.Info
+}
+
+func newEnclosingFuncs(pkg packageInfo) enclosingFuncs {
+	var decls []*ast.FuncDecl
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				decls = append(decls, funcDecl)
+			}
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return decls[i].Pos() < decls[j].Pos()
+	})
+	return enclosingFuncs{
+		decls: decls,
+		info:  pkg.Info,
+	}
+}
+
+// decl returns the function declaration that contains the provided position. Returns nil if the position is not in a
+// function declaration.
+func (e enclosingFuncs) decl(pos token.Pos) *ast.FuncDecl {
+	idx := sort.Search(len(e.decls), func(i int) bool {
+		return e.decls[i].End() > pos
+	})
+	if idx == len(e.decls) || e.decls[idx].Pos() > pos {
+		return nil
+	}
+	return e.decls[idx]
+}
+
+// name returns the qualified name of the function that contains the provided position with vendor directories removed.
+// Returns an empty string if the position is not in a function declaration.
+func (e enclosingFuncs) name(pos token.Pos) string {
+	decl := e.decl(pos)
+	if decl == nil {
+		return ""
+	}
+	fn, ok := e.info.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return ""
+	}
+	return toFuncWithNoIdentifiersRemoveVendor(fn).FullName()
+}
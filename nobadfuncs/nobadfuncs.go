@@ -22,107 +22,146 @@ import (
 	"io"
 	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	"golang.org/x/tools/go/packages"
 )
 
+// Options are options for a check that are independent of the configuration of the references that are checked.
+type Options struct {
+	// NewFromRev, if non-empty, restricts the reported references to those on lines that were added or modified in the
+	// working tree relative to the specified git revision. Packages are still loaded in their entirety.
+	NewFromRev string
+	// NewFromPatch, if non-empty, is the path to a unified diff file. Reported references are restricted to those on
+	// lines that were added or modified by the diff. Relative file paths in the diff are resolved against the directory
+	// in which the check is run.
+	NewFromPatch string
+	// DiscoverConfig, if true, resolves the configuration files named ConfigFileName in the working directory and its
+	// parents and in the module root directory of each checked package and each of its subdirectories up to the
+	// directory of the package on top of the provided configuration, so that each package is checked against the
+	// configuration of its directory (see configHierarchy.configFor). Files in deeper directories take precedence: they
+	// can add rules and relax inherited rules using overrides. If the provided configuration is empty and no files
+	// apply to the checked packages, the packages are not loaded.
+	DiscoverConfig bool
+	// CodeOwners, if non-empty, is the path of a CODEOWNERS file that is used to determine the owners of each finding
+	// (see FindCodeOwners).
+	CodeOwners string
+	// Format is the output format used by PrintBadRefs. Defaults to OutputFormatText.
+	Format OutputFormat
+	// GroupBy, if non-empty, groups the output of PrintBadRefs. Grouping by owner requires CodeOwners and is only
+	// supported by OutputFormatText and OutputFormatJSON.
+	GroupBy GroupBy
+	// IncludeSuppressed specifies that findings that are whitelisted using an "// OK: [reason]" comment are returned
+	// with their Suppression set rather than omitted. Always true for OutputFormatHTML.
+	IncludeSuppressed bool
+}
+
 // FuncRef is a reference to a specific function. Matches the string representation of *types.Func, which is of the
 // form "func (*net/http.Client).Do(req *net/http.Request) (*net/http.Response, error)".
 type FuncRef string
 
 // PrintAllFuncRefs prints all of the function references in the provided packages.
 func PrintAllFuncRefs(pkgs []string, dir string, w io.Writer) error {
-	_, err := printFuncRefUsages(pkgs, nil, dir, w)
-	return err
+	loadedPkgs, err := loadPackages(pkgs, dir, false)
+	if err != nil {
+		return err
+	}
+	for _, loadedPkg := range loadedPkgs {
+		visitInOrder(filePosFuncRefMap(loadedPkg.TypesInfo.Uses, loadedPkg.Fset), func(pos token.Position, ref FuncRef) {
+			_, _ = fmt.Fprintf(w, "%s: %s\n", pos.String(), ref)
+		})
+	}
+	return nil
 }
 
 // PrintBadFuncRefs prints the "bad" function references (the function references that match those provided in sigs).
 // Returns an error if the check fails or if any bad references are found.
 func PrintBadFuncRefs(pkgs []string, sigs map[string]string, dir string, w io.Writer) error {
-	ok, err := printBadFuncRefsHelper(pkgs, sigs, dir, w)
-	if err != nil {
+	return PrintBadRefs(pkgs, Config{Funcs: sigs}, dir, Options{}, w)
+}
+
+// PrintBadRefs prints the references in the provided packages that violate the provided configuration. Returns an
+// error if the check fails or if any bad references are found.
+func PrintBadRefs(pkgs []string, cfg Config, dir string, opts Options, w io.Writer) error {
+	if err := validateOutput(opts); err != nil {
 		return err
 	}
-	if !ok {
-		return fmt.Errorf("")
+	if opts.Format == OutputFormatHTML {
+		opts.IncludeSuppressed = true
 	}
-	return nil
-}
-
-func printBadFuncRefsHelper(pkgs []string, sigs map[string]string, dir string, w io.Writer) (bool, error) {
-	if len(sigs) == 0 {
-		// if there are no signatures, there will be no output
-		return true, nil
+	var findings []Finding
+	// if there are no rules, there are no findings
+	if !cfg.empty() || opts.DiscoverConfig {
+		var err error
+		if findings, err = Check(pkgs, cfg, dir, opts); err != nil {
+			return err
+		}
 	}
-	return printFuncRefUsages(pkgs, sigs, dir, w)
+	if err := printFindings(findings, opts, w); err != nil {
+		return err
+	}
+	for _, finding := range findings {
+		if finding.Suppression == "" {
+			return fmt.Errorf("")
+		}
+	}
+	return nil
 }
 
-func printFuncRefUsages(pkgs []string, sigs map[string]string, dir string, stdout io.Writer) (bool, error) {
+// loadPackages loads the syntax, type information and module information for the provided packages and all of their
+// dependencies. If tests is true, the test variants of the packages (see isTestVariant) and their test main packages
+// are also loaded.
+func loadPackages(pkgs []string, dir string, tests bool) ([]*packages.Package, error) {
 	loadedPkgs, err := packages.Load(&packages.Config{
-		Mode: packages.LoadAllSyntax,
-		Dir:  dir,
+		Mode:  packages.LoadAllSyntax | packages.NeedModule,
+		Dir:   dir,
+		Tests: tests,
 	}, pkgs...)
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to load packages")
+		return nil, errors.Wrapf(err, "failed to load packages")
 	}
+	return loadedPkgs, nil
+}
 
-	noBadRefs := true
-	for _, loadedPkg := range loadedPkgs {
-		funcRefMap := filePosFuncRefMap(loadedPkg.TypesInfo.Uses, loadedPkg.Fset, sigs)
-		if len(sigs) == 0 {
-			// "all" mode: print all references
-			visitInOrder(funcRefMap, func(pos token.Position, ref FuncRef) {
-				_, _ = fmt.Fprintf(stdout, "%s: %s\n", pos.String(), ref)
-			})
-			continue
-		}
-
-		commentMap := fileLineCommentMap(loadedPkg.Fset, loadedPkg.Syntax)
+// isTestVariant returns true if the provided package is the variant of a package that is compiled with its test files
+// (whose ID is of the form "p [p.test]") or an external test package (whose ID is of the form "p_test [p.test]").
+func isTestVariant(pkg *packages.Package) bool {
+	return strings.HasSuffix(pkg.ID, ".test]")
+}
 
-		// filter out any matches that have a whitelist comment
-		filterFuncRefs(funcRefMap, commentMap, okCommentRegxp.MatchString)
+// isTestMain returns true if the provided package is the generated main package of a test binary.
+func isTestMain(pkg *packages.Package) bool {
+	return strings.HasSuffix(pkg.ID, ".test")
+}
 
-		visitInOrder(funcRefMap, func(pos token.Position, ref FuncRef) {
-			reason, ok := sigs[string(ref)]
-			if !ok {
-				return
-			}
-			noBadRefs = false
-			if reason == "" {
-				reason = fmt.Sprintf("references to %q are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", ref)
-			}
-			_, _ = fmt.Fprintf(stdout, "%s: %s\n", pos.String(), reason)
-		})
+// testFiles returns the "_test.go" files of the provided package.
+func testFiles(pkg packageInfo) []*ast.File {
+	var out []*ast.File
+	for _, file := range pkg.Files {
+		if strings.HasSuffix(pkg.Fset.Position(file.Pos()).Filename, "_test.go") {
+			out = append(out, file)
+		}
 	}
-	return noBadRefs, nil
+	return out
 }
 
 // matches a single-line comment beginning with "// OK: " followed by at least one non-whitespace character.
 var okCommentRegxp = regexp.MustCompile(regexp.QuoteMeta(`// OK: `) + `\S.*`)
 
-func filterFuncRefs(funcRefs map[string]map[token.Position]FuncRef, comments map[string]map[int]string, filter func(string) bool) {
-	for file, posToFuncRef := range funcRefs {
-		lineToComment, ok := comments[file]
-		if !ok {
-			// no comments in the file; continue
-			continue
-		}
-
-		for pos := range posToFuncRef {
-			// get comment on the line before the function reference
-			commentForLine, ok := lineToComment[pos.Line-1]
-			if !ok {
-				// if no comment exists, continue
-				continue
-			}
+// isWhitelisted returns true if the line before the provided position has a comment that whitelists references.
+func isWhitelisted(comments map[string]map[int]string, pos token.Position) bool {
+	return suppression(comments, pos) != ""
+}
 
-			// if filter matches, remove entry from map
-			if filter(commentForLine) {
-				delete(posToFuncRef, pos)
-			}
-		}
+// suppression returns the reason of the comment that whitelists references on the line before the provided position.
+// Returns an empty string if there is no such comment.
+func suppression(comments map[string]map[int]string, pos token.Position) string {
+	commentForLine, ok := comments[pos.Filename][pos.Line-1]
+	if !ok || !okCommentRegxp.MatchString(commentForLine) {
+		return ""
 	}
+	return strings.TrimSpace(strings.TrimPrefix(okCommentRegxp.FindString(commentForLine), "// OK: "))
 }
 
 func visitInOrder(funcRefs map[string]map[token.Position]FuncRef, visitor func(token.Position, FuncRef)) {
@@ -180,9 +219,8 @@ func fileLineCommentMap(fset *token.FileSet, files []*ast.File) map[string]map[i
 }
 
 // filePosFuncRefMap returns a map from filename to position to FuncRef for all of the function references in the
-// specified package. If "sigs" is non-empty, then only function signature that match a key in the "sigs" map are
-// included; otherwise, all function references are returned.
-func filePosFuncRefMap(uses map[*ast.Ident]types.Object, fset *token.FileSet, sigs map[string]string) map[string]map[token.Position]FuncRef {
+// specified package.
+func filePosFuncRefMap(uses map[*ast.Ident]types.Object, fset *token.FileSet) map[string]map[token.Position]FuncRef {
 	fileToPosToFuncRef := make(map[string]map[token.Position]FuncRef)
 
 	var keys []*ast.Ident
@@ -203,13 +241,6 @@ func filePosFuncRefMap(uses map[*ast.Ident]types.Object, fset *token.FileSet, si
 		funcPtr = toFuncWithNoIdentifiersRemoveVendor(funcPtr)
 		currSig := FuncRef(funcPtr.String())
 
-		if len(sigs) > 0 {
-			if _, ok := sigs[string(currSig)]; !ok {
-				// if sigs is non-empty, skip any entries that don't match the signature
-				continue
-			}
-		}
-
 		currPos := fset.Position(id.Pos())
 		posToRef := fileToPosToFuncRef[currPos.Filename]
 		if posToRef == nil {
@@ -143,6 +143,34 @@ func MyFunction() {
 				return fmt.Sprintf("%s:9:21: TEST: don't use this please\n", path.Join(testDir, "foo/foo.go"))
 			},
 		},
+		{
+			name: "methods of generic types match both generic and instantiated signatures",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"sync/atomic"
+)
+
+func MyFunction() {
+	var p atomic.Pointer[int]
+	p.Store(nil)
+	_ = p.Load()
+}
+`,
+				},
+			},
+			sigs: map[string]string{
+				"func (*sync/atomic.Pointer).Store(*T)":   "generic",
+				"func (*sync/atomic.Pointer).Load() *int": "instantiated",
+			},
+			want: func(testDir string) string {
+				return fmt.Sprintf("%s:10:4: generic\n%s:11:8: instantiated\n", path.Join(testDir, "foo/foo.go"), path.Join(testDir, "foo/foo.go"))
+			},
+		},
 		{
 			name: "function with matching signature is skipped when whitelisted",
 			specs: []gofiles.GoFileSpec{
@@ -365,6 +393,38 @@ func (b BarType) Bar(in BarType) BarType {
 				}, "\n") + "\n"
 			},
 		},
+		{
+			name: "prints vendored aliases but omits vendor from package",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"github.com/bar"
+)
+
+func MyFunction() {
+	bar.Take("")
+}
+`,
+				},
+				{
+					RelPath: "vendor/github.com/bar/bar.go",
+					Src: `
+package bar
+
+type Alias = string
+
+func Take(in Alias) {}
+`,
+				},
+			},
+			want: func(testDir string) string {
+				return fmt.Sprintf("%s:9:6: func github.com/bar.Take(github.com/bar.Alias)", path.Join(testDir, "foo/foo.go")) + "\n"
+			},
+		},
 		{
 			name: "deals with circular type definitions",
 			specs: []gofiles.GoFileSpec{
@@ -459,3 +519,1350 @@ func LexEnter(l *Lexer) StateFn {
 		})
 	}
 }
+
+func TestPrintBadRefs(t *testing.T) {
+	prevValue := os.Getenv("GOFLAGS")
+	defer func() {
+		_ = os.Setenv("GOFLAGS", prevValue)
+	}()
+	err := os.Setenv("GOFLAGS", "-mod=vendor")
+	require.NoError(t, err)
+
+	for i, currCase := range []struct {
+		name  string
+		specs []gofiles.GoFileSpec
+		cfg   nobadfuncs.Config
+		want  func(testDir string) string
+	}{
+		{
+			name: "deny rule with patterns",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"net/http"
+	"os"
+)
+
+func MyFunction() {
+	http.DefaultClient.Do(nil)
+	http.DefaultClient.Get("")
+	os.Exit(1)
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:      "no-client-methods",
+						Refs:    []string{"(*net/http.Client).*"},
+						Message: "No client",
+					},
+					{
+						ID:   "no-exit",
+						Refs: []string{"os.Exit"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:10:21: No client", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:11:21: No client", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:12:5: references to \"func os.Exit(int)\" are not allowed. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "allow rule restricts references to a package",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+func MyFunction() {
+	fmt.Println(os.Getenv("FOO"))
+	_, _ = os.LookupEnv("FOO")
+	os.Exit(1)
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Allow: []nobadfuncs.AllowRule{
+					{
+						From:    []string{"os"},
+						Refs:    []string{"os.Getenv", "os.LookupEnv"},
+						Message: "Only environment access",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return fmt.Sprintf("%s:12:5: Only environment access\n", path.Join(testDir, "foo/foo.go"))
+			},
+		},
+		{
+			name: "allow rule scoped to packages",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "plugins/foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"fmt"
+	"strings"
+)
+
+func Foo() {
+	fmt.Println(strings.ToUpper(bar()))
+}
+
+func bar() string {
+	return ""
+}
+`,
+				},
+				{
+					RelPath: "bar/bar.go",
+					Src: `
+package bar
+
+import (
+	"strings"
+)
+
+var Bar = strings.ToUpper("")
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Allow: []nobadfuncs.AllowRule{
+					{
+						Packages: []string{"plugins/..."},
+						Refs:     []string{"fmt"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return fmt.Sprintf("%s:10:22: references to \"func strings.ToUpper(string) string\" are not in the allow-list. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.\n", path.Join(testDir, "plugins/foo/foo.go"))
+			},
+		},
+		{
+			name: "references across layers",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "domain/domain.go",
+					Src: `
+package domain
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/transport"
+)
+
+var Handler = transport.Handle
+`,
+				},
+				{
+					RelPath: "transport/transport.go",
+					Src: `
+package transport
+
+func Handle() {}
+`,
+				},
+				{
+					RelPath: "internal/storage/storage.go",
+					Src: `
+package storage
+
+type Store struct{}
+`,
+				},
+				{
+					RelPath: "internal/service/service.go",
+					Src: `
+package service
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/internal/storage"
+)
+
+var Store storage.Store
+`,
+				},
+				{
+					RelPath: "cmd/cmd.go",
+					Src: `
+package cmd
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/internal/storage"
+)
+
+var Store storage.Store
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Layers: []nobadfuncs.Layer{
+					{
+						Name:     "domain",
+						Packages: []string{"domain/..."},
+					},
+					{
+						Name:     "transport",
+						Packages: []string{"transport/..."},
+						Allow:    []string{"domain"},
+					},
+					{
+						Name:       "storage",
+						Packages:   []string{"internal/storage"},
+						Restricted: true,
+					},
+					{
+						Name:     "service",
+						Packages: []string{"internal/service"},
+						Allow:    []string{"storage"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:8:19: references to \"type github.com/palantir/go-nobadfuncs-test/internal/storage.Store\" in layer \"storage\" are only allowed from layers that allow it. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "cmd/cmd.go")),
+					fmt.Sprintf("%s:8:25: references to \"func github.com/palantir/go-nobadfuncs-test/transport.Handle()\" in layer \"transport\" are not allowed from layer \"domain\". Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "domain/domain.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "references through reflection, plugin lookups and linkname",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"net/http"
+	"plugin"
+	"reflect"
+	_ "unsafe"
+)
+
+//go:linkname nanotime runtime.nanotime
+func nanotime() int64
+
+func MyFunction(p *plugin.Plugin, v reflect.Value) {
+	reflect.ValueOf(http.DefaultClient).MethodByName("Do")
+	reflect.ValueOf(http.DefaultClient).MethodByName("CloseIdleConnections")
+	v.MethodByName("Do")
+	p.Lookup("Exit")
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Funcs: map[string]string{
+					"func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)": "No",
+				},
+				Deny: []nobadfuncs.Rule{
+					{
+						Refs:    []string{"os.Exit"},
+						Message: "No exit",
+					},
+					{
+						Refs:    []string{"runtime.*"},
+						Message: "No runtime",
+					},
+				},
+				// indirect references are only checked against function signatures and deny rules, so the reflected
+				// reference to CloseIdleConnections is not reported
+				Allow: []nobadfuncs.AllowRule{
+					{
+						From: []string{"(*net/http.Client).*"},
+						Refs: []string{"(*net/http.Client).Do"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:11:1: No runtime (low confidence: referenced via go:linkname)", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:15:51: No (low confidence: referenced via reflection)", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:17:17: No (low confidence: referenced via reflection)", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:18:11: No exit (low confidence: referenced via plugin lookup)", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "indirect references match the statically known receiver and the context of the lookup",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"plugin"
+	"reflect"
+	"strings"
+)
+
+func MyFunction(p *plugin.Plugin, v reflect.Value) {
+	reflect.ValueOf(&strings.Builder{}).MethodByName("Do")
+	v.MethodByName("Exit")
+	p.Lookup("Do")
+	p.Lookup("Exit")
+	go func() {
+		p.Lookup("Exit")
+	}()
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Funcs: map[string]string{
+					"func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)": "No",
+				},
+				Deny: []nobadfuncs.Rule{
+					{
+						Refs:    []string{"os.Exit"},
+						Context: []string{"goroutine"},
+						Message: "No exit",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return fmt.Sprintf("%s:16:12: No exit (low confidence: referenced via plugin lookup)\n", path.Join(testDir, "foo/foo.go"))
+			},
+		},
+		{
+			name: "promoted methods, defined types and aliases match when configured",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"net/http"
+
+	"github.com/palantir/go-nobadfuncs-test/bar"
+)
+
+type Wrapper struct {
+	*http.Client
+}
+
+type MyClient http.Client
+
+func (c *MyClient) Do(req *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func MyFunction(w Wrapper, c *MyClient) {
+	w.Do(nil)
+	c.Do(nil)
+	bar.Take(nil)
+}
+`,
+				},
+				{
+					RelPath: "bar/bar.go",
+					Src: `
+package bar
+
+import (
+	"net/http"
+)
+
+type Alias = http.Client
+
+func Take(c *Alias) {}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Funcs: map[string]string{
+					"func github.com/palantir/go-nobadfuncs-test/bar.Take(*net/http.Client)": "No Take",
+				},
+				Deny: []nobadfuncs.Rule{
+					{
+						Refs:    []string{"(*github.com/palantir/go-nobadfuncs-test/foo.Wrapper).Do"},
+						Message: "No Wrapper.Do",
+					},
+					{
+						Refs:    []string{"(*net/http.Client).Do"},
+						Message: "No Do",
+					},
+				},
+				Match: nobadfuncs.MatchConfig{
+					PromotedMethods: true,
+					DefinedTypes:    true,
+					Aliases:         true,
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:21:4: No Wrapper.Do", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:22:4: No Do", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:23:6: No Take", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "promoted methods do not match embedding type by default",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `
+package foo
+
+import (
+	"net/http"
+)
+
+type Wrapper struct {
+	*http.Client
+}
+
+func MyFunction(w Wrapper) {
+	w.Do(nil)
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Deny: []nobadfuncs.Rule{
+					{
+						Refs:    []string{"(*github.com/palantir/go-nobadfuncs-test/foo.Wrapper).Do"},
+						Message: "No Wrapper.Do",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return ""
+			},
+		},
+		{
+			name: "declaration rules",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "api/api.go",
+					Src: `
+package api
+
+import (
+	"context"
+)
+
+type Resp struct{}
+
+func (r Resp) MarshalJSON() ([]byte, error) {
+	return nil, nil
+}
+
+type ValueErr struct{}
+
+func (e ValueErr) Error() string {
+	return ""
+}
+
+type PtrErr struct{}
+
+func (e *PtrErr) Error() string {
+	return ""
+}
+
+type Ctx struct {
+	context.Context
+}
+
+type Errorer interface {
+	Error() string
+}
+`,
+				},
+				{
+					RelPath: "other/other.go",
+					Src: `
+package other
+
+type Resp struct{}
+
+// OK: marshals a legacy format
+func (r Resp) MarshalJSON() ([]byte, error) {
+	return nil, nil
+}
+
+func (r Resp) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+type Unmarshaler interface {
+	UnmarshalJSON([]byte) error
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Declarations: []nobadfuncs.DeclarationRule{
+					{
+						Name:      "Error",
+						Signature: "func() string",
+						Receiver:  "value",
+						Message:   "Error must use a pointer receiver",
+					},
+					{
+						Name:     "*JSON",
+						Receiver: "method",
+						Packages: []string{"other"},
+					},
+					{
+						Implements: "context.Context",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:16:19: Error must use a pointer receiver", path.Join(testDir, "api/api.go")),
+					fmt.Sprintf("%s:26:6: types that implement \"context.Context\" are not allowed. Remove this declaration or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "api/api.go")),
+					fmt.Sprintf("%s:11:15: declarations of \"func (github.com/palantir/go-nobadfuncs-test/other.Resp).UnmarshalJSON([]byte) error\" are not allowed. Remove this declaration or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "other/other.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "message templates",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+func Read() {
+	_, _ = ioutil.ReadFile("foo")
+}
+
+func (f Foo) Exit() {
+	os.Exit(1)
+}
+
+type Foo struct{}
+
+func Pid() int {
+	return os.Getpid()
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				// reasons in funcs are not templates
+				Funcs: map[string]string{
+					"func os.Getpid() int": "do not use {{.Signature}}",
+				},
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:      "no-exit",
+						Refs:    []string{"os.Exit"},
+						Message: "{{.Caller}} calls {{.Signature}}. {{.Suffix}}",
+					},
+					{
+						ID:          "no-ioutil",
+						Refs:        []string{"io/ioutil.*"},
+						Message:     "[{{.ID}}] {{.Package}}: use {{.Replacement}} instead (see {{.DocURL}})",
+						Replacement: "os.ReadFile",
+						DocURL:      "https://pkg.go.dev/io/ioutil",
+					},
+				},
+				MessageSuffix: "Ask #platform for an exception.",
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:9:16: [no-ioutil] github.com/palantir/go-nobadfuncs-test/foo: use os.ReadFile instead (see https://pkg.go.dev/io/ioutil)", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:13:5: (github.com/palantir/go-nobadfuncs-test/foo.Foo).Exit calls func os.Exit(int). Ask #platform for an exception.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:19:12: do not use {{.Signature}}", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "presets, includes and overrides",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "shared/nobadfuncs.yml",
+					Src: `presets: [global-state]
+deny:
+  - id: no-exit
+    refs: ["os.Exit"]
+overrides:
+  - id: global-state-log
+    packages: ["cli/..."]
+`,
+				},
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"log"
+	"net/http"
+	"os"
+)
+
+func Foo() {
+	log.SetFlags(0)
+	os.Setenv("KEY", "value")
+	_, _ = http.Get("http://localhost")
+	os.Exit(1)
+}
+`,
+				},
+				{
+					RelPath: "cli/cli.go",
+					Src: `package cli
+
+import (
+	"log"
+)
+
+func Main() {
+	log.SetFlags(0)
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Include: []string{"shared/nobadfuncs.yml"},
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:      "no-exit",
+						Refs:    []string{"os.Exit"},
+						Message: "return an error instead",
+					},
+				},
+				Overrides: []nobadfuncs.RuleOverride{
+					{
+						ID:       "global-state-env",
+						Disabled: true,
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:8:6: func log.SetFlags(int) modifies the global logger: accept a logger instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "cli/cli.go")),
+					fmt.Sprintf("%s:12:14: func net/http.Get(string) (*net/http.Response, error) uses the global HTTP client or mux: accept an *http.Client or *http.ServeMux instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:13:5: return an error instead", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "deprecated preset and deprecated objects",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "lib/lib.go",
+					Src: `package lib
+
+// Old does things.
+//
+// Deprecated: use New instead.
+func Old() {}
+
+func New() {
+	Old()
+}
+
+// Legacy is a client.
+//
+// Deprecated: use Client instead.
+type Legacy struct{}
+`,
+				},
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"strings"
+
+	"github.com/palantir/go-nobadfuncs-test/lib"
+)
+
+func Foo() {
+	lib.Old()
+	var _ lib.Legacy
+	_ = strings.Title("foo")
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Presets: []string{"deprecated-stdlib"},
+				Deprecated: nobadfuncs.DeprecatedConfig{
+					Enabled: true,
+					Refs:    []string{"github.com/palantir/go-nobadfuncs-test/..."},
+				},
+				Overrides: []nobadfuncs.RuleOverride{
+					{
+						ID:      "deprecated",
+						Message: "{{.Signature}} is deprecated: {{.Replacement}}",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:10:6: func github.com/palantir/go-nobadfuncs-test/lib.Old() is deprecated: use New instead.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:11:12: type github.com/palantir/go-nobadfuncs-test/lib.Legacy is deprecated: use Client instead.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:12:14: func strings.Title(string) string is deprecated: The rule Title uses for word boundaries does not handle Unicode punctuation properly. Use golang.org/x/text/cases instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "nondeterminism preset scoped to packages",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "sim/sim.go",
+					Src: `package sim
+
+import (
+	"math/rand"
+	"time"
+)
+
+type Counts map[string]int
+
+func Step(counts Counts, ids []string) int {
+	total := rand.Intn(10)
+	for _, count := range counts {
+		total += count
+	}
+	// OK: order does not matter for a sum
+	for _, count := range counts {
+		total += count
+	}
+	for range ids {
+		total++
+	}
+	_ = time.Now()
+	return total
+}
+`,
+				},
+				{
+					RelPath: "server/server.go",
+					Src: `package server
+
+import (
+	"time"
+)
+
+func Start(m map[string]int) {
+	_ = time.Now()
+	for range m {
+	}
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Presets: []string{"nondeterminism"},
+				Overrides: []nobadfuncs.RuleOverride{
+					{
+						ID:       "nondeterminism",
+						Packages: []string{"sim/..."},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:11:16: func math/rand.Intn(int) int uses global randomness: use a random source that is seeded explicitly instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "sim/sim.go")),
+					fmt.Sprintf("%s:12:2: range over a map of type \"github.com/palantir/go-nobadfuncs-test/sim.Counts\" has a nondeterministic iteration order: iterate over the sorted keys instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "sim/sim.go")),
+					fmt.Sprintf("%s:22:11: func time.Now() time.Time depends on the wall clock: use an injected clock instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "sim/sim.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "crypto preset and field rules",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "auth/auth.go",
+					Src: `package auth
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+)
+
+func Config(insecure bool) *tls.Config {
+	_ = md5.Sum(nil)
+	cfg := &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+	}
+	cfg.InsecureSkipVerify = insecure
+	cfg.MinVersion = tls.VersionTLS13
+	cfg.InsecureSkipVerify = false
+	cfg.InsecureSkipVerify = !false
+	return cfg
+}
+`,
+				},
+				{
+					RelPath: "auth/nonce.go",
+					Src: `package auth
+
+import "math/rand"
+
+func Nonce() int {
+	return rand.Int()
+}
+`,
+				},
+				{
+					RelPath: "sim/sim.go",
+					Src: `package sim
+
+import "math/rand"
+
+func Roll() int {
+	return rand.Intn(6)
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Presets: []string{"crypto"},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:9:10: func crypto/md5.Sum([]byte) [16]byte uses a broken hash function: use crypto/sha256 or crypto/sha512 (or crypto/hmac for message authentication) instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "auth/auth.go")),
+					fmt.Sprintf("%s:11:3: crypto/tls.Config.InsecureSkipVerify disables verification of the certificate chain and host name: configure the RootCAs field (or VerifyPeerCertificate for custom verification) instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "auth/auth.go")),
+					fmt.Sprintf("%s:12:3: crypto/tls.Config.MinVersion allows TLS versions older than 1.2: use tls.VersionTLS12 or tls.VersionTLS13 instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "auth/auth.go")),
+					fmt.Sprintf("%s:17:6: crypto/tls.Config.InsecureSkipVerify disables verification of the certificate chain and host name: configure the RootCAs field (or VerifyPeerCertificate for custom verification) instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "auth/auth.go")),
+					fmt.Sprintf("%s:6:14: func math/rand.Int() int is not cryptographically secure: use crypto/rand instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "auth/nonce.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "crypto preset rules are overridden individually",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "sim/sim.go",
+					Src: `package sim
+
+import (
+	"crypto/md5"
+	"math/rand"
+)
+
+func Roll() int {
+	_ = md5.Sum(nil)
+	return rand.Intn(6)
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Presets: []string{"crypto"},
+				Overrides: []nobadfuncs.RuleOverride{
+					{
+						ID:       "crypto-weak-hash",
+						Disabled: true,
+					},
+					{
+						ID:       "crypto-math-rand",
+						Packages: []string{"sim"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return fmt.Sprintf("%s:10:14: func math/rand.Intn(int) int is not cryptographically secure: use crypto/rand instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.\n", path.Join(testDir, "sim/sim.go"))
+			},
+		},
+		{
+			name: "annotations in source of referenced objects",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "lib/client/client.go",
+					Src: `package client
+
+// NewClient returns a client.
+//
+//nobadfuncs:deny use NewClientWithRetry instead
+func NewClient() {}
+
+func NewClientWithRetry() {
+	NewClient()
+}
+
+// Reset resets the state of all clients.
+//
+//nobadfuncs:restrict internal/... github.com/org/admin/...
+func Reset() {}
+`,
+				},
+				{
+					RelPath: "internal/admin/admin.go",
+					Src: `package admin
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/lib/client"
+)
+
+func Admin() {
+	client.Reset()
+	client.NewClient()
+}
+`,
+				},
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/lib/client"
+)
+
+func Foo() {
+	client.NewClientWithRetry()
+	client.Reset()
+	// OK: resetting is required by the test harness
+	client.Reset()
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Deny: []nobadfuncs.Rule{
+					{
+						Refs: []string{"func os.Exit(int)"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:9:9: references to \"func github.com/palantir/go-nobadfuncs-test/lib/client.Reset()\" are only allowed from internal/..., github.com/org/admin/.... Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:9:9: references to \"func github.com/palantir/go-nobadfuncs-test/lib/client.NewClient()\" are not allowed: use NewClientWithRetry instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "internal/admin/admin.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "caller rules",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "db/db.go",
+					Src: `package db
+
+func UnsafeRawQuery(query string) {}
+
+func Query(query string) {
+	UnsafeRawQuery(query)
+}
+`,
+				},
+				{
+					RelPath: "testutil/testutil.go",
+					Src: `package testutil
+
+func TempDB() {}
+`,
+				},
+				{
+					RelPath: "internal/migrations/migrations.go",
+					Src: `package migrations
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/db"
+)
+
+func Migrate() {
+	db.UnsafeRawQuery("ALTER TABLE foo")
+}
+`,
+				},
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"github.com/palantir/go-nobadfuncs-test/db"
+	"github.com/palantir/go-nobadfuncs-test/testutil"
+)
+
+func Foo() {
+	db.UnsafeRawQuery("SELECT 1")
+	db.Query("SELECT 1")
+	testutil.TempDB()
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Callers: []nobadfuncs.CallerRule{
+					{
+						ID:       "raw-query",
+						Refs:     []string{"github.com/palantir/go-nobadfuncs-test/db.UnsafeRawQuery"},
+						Packages: []string{"internal/migrations/..."},
+					},
+					{
+						ID:    "testutil",
+						Refs:  []string{"github.com/palantir/go-nobadfuncs-test/testutil"},
+						Files: []string{"*_test.go"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:9:5: references to \"func github.com/palantir/go-nobadfuncs-test/db.UnsafeRawQuery(string)\" from package github.com/palantir/go-nobadfuncs-test/foo are only allowed from packages internal/migrations/.... Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:11:11: references to \"func github.com/palantir/go-nobadfuncs-test/testutil.TempDB()\" from package github.com/palantir/go-nobadfuncs-test/foo are only allowed from files *_test.go. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "deny rules restricted to syntactic contexts",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+)
+
+var resp, _ = http.Get("http://example.com")
+
+var client = func() *http.Client {
+	http.Get("http://example.com")
+	return nil
+}
+
+func init() {
+	http.Get("http://example.com")
+}
+
+func Foo(lines []string, mu *sync.Mutex) {
+	for _, line := range lines {
+		regexp.MustCompile(line)
+		func() {
+			regexp.MustCompile(line)
+		}()
+	}
+	go func() {
+		log.Fatal("failed")
+	}()
+	log.Fatal("failed")
+	mu.Lock()
+	os.ReadFile("foo")
+	mu.Unlock()
+	os.ReadFile("foo")
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) > 0 {
+		os.ReadFile("foo")
+	}
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:      "compile-in-loop",
+						Refs:    []string{"regexp.MustCompile"},
+						Context: []string{"loop"},
+					},
+					{
+						ID:      "network-in-init",
+						Refs:    []string{"net/http.Get"},
+						Context: []string{"init"},
+					},
+					{
+						ID:      "fatal-in-goroutine",
+						Refs:    []string{"log.Fatal"},
+						Context: []string{"goroutine"},
+					},
+					{
+						ID:      "io-while-locked",
+						Refs:    []string{"os.ReadFile"},
+						Context: []string{"locked"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:11:20: references to \"func net/http.Get(string) (*net/http.Response, error)\" are not allowed during package initialization. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:19:7: references to \"func net/http.Get(string) (*net/http.Response, error)\" are not allowed during package initialization. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:24:10: references to \"func regexp.MustCompile(string) *regexp.Regexp\" are not allowed in loops. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:30:7: references to \"func log.Fatal(...any)\" are not allowed in goroutines. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:34:5: references to \"func os.ReadFile(string) ([]byte, error)\" are not allowed while a mutex is locked. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:40:6: references to \"func os.ReadFile(string) ([]byte, error)\" are not allowed while a mutex is locked. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "context preset and enclosing parameter rules",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+)
+
+func WithContext(ctx context.Context, r *http.Request) {
+	_ = context.Background()
+	_ = exec.Command("ls")
+	go func() {
+		_ = context.TODO()
+	}()
+	_ = r.Context()
+}
+
+func WithoutContext() {
+	_ = context.Background()
+	_ = exec.Command("ls")
+	_ = func(ctx context.Context) {
+		http.Get("http://example.com")
+	}
+}
+
+func Handle(w http.ResponseWriter, r *http.Request) {
+	_ = context.Background()
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Presets: []string{"context"},
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:             "request-context",
+						Refs:           []string{"context.Background"},
+						EnclosingParam: "*net/http.Request",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:10:14: func context.Background() context.Context creates a new context in a function that receives one: use the context.Context parameter (or a context derived from it) instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:11:11: func os/exec.Command(string, ...string) *os/exec.Cmd ignores the context of the enclosing function: use os/exec.CommandContext with the context.Context parameter instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:13:15: func context.TODO() context.Context creates a new context in a function that receives one: use the context.Context parameter (or a context derived from it) instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:22:8: func net/http.Get(string) (*net/http.Response, error) ignores the context of the enclosing function: use net/http.NewRequestWithContext with the context.Context parameter instead. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:27:14: references to \"func context.Background() context.Context\" are not allowed in functions that have a parameter of type *net/http.Request. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "sequence rules",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"context"
+	"net/http"
+)
+
+func Cancel(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	_ = cancel
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+	_ = ctx2
+	ctx3, _ := context.WithCancel(ctx)
+	return ctx3
+}
+
+func Forward(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, cancel
+}
+
+func Get(c *http.Client, req *http.Request) (int, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func GetAndClose(c *http.Client, req *http.Request) (int, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+`,
+				},
+				{
+					RelPath: "foo/foo_test.go",
+					Src: `package foo
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	os.Setenv("FOO", "bar")
+	t.Parallel()
+	t.Run("sub", func(t *testing.T) {
+		os.Setenv("FOO", "bar")
+	})
+	// OK: the variable is not read by any other test
+	os.Setenv("FOO", "bar")
+	os.Setenv("FOO", "bar")
+}
+`,
+				},
+				{
+					RelPath: "foo/external_test.go",
+					Src: `package foo_test
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExternal(t *testing.T) {
+	t.Parallel()
+	os.Unsetenv("FOO")
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				// test files are only checked against sequence rules
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:   "no-unsetenv",
+						Refs: []string{"os.Unsetenv"},
+					},
+				},
+				Sequences: []nobadfuncs.SequenceRule{
+					{
+						ID:       "cancel",
+						Calls:    []string{"context.WithCancel"},
+						Result:   1,
+						MustCall: "()",
+					},
+					{
+						ID:       "close-body",
+						Calls:    []string{"(*net/http.Client).Do"},
+						MustCall: "Body.Close",
+					},
+					{
+						ID:       "setenv-parallel",
+						Calls:    []string{"os.Setenv", "os.Unsetenv"},
+						NotAfter: []string{"(*testing.T).Parallel"},
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:9:25: result 1 of \"func context.WithCancel(context.Context) (context.Context, context.CancelFunc)\" must be called or deferred. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:14:21: result 1 of \"func context.WithCancel(context.Context) (context.Context, context.CancelFunc)\" must be called or deferred. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:24:17: Body.Close must be called on result 0 of \"func (*net/http.Client).Do(*net/http.Request) (*net/http.Response, error)\". Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:16:5: \"func os.Setenv(string, string) error\" must not be called after \"func (*testing.T).Parallel()\". Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo_test.go")),
+					fmt.Sprintf("%s:10:5: \"func os.Unsetenv(string) error\" must not be called after \"func (*testing.T).Parallel()\". Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/external_test.go")),
+				}, "\n") + "\n"
+			},
+		},
+		{
+			name: "deny rules restricted to calls whose results are discarded",
+			specs: []gofiles.GoFileSpec{
+				{
+					RelPath: "foo/foo.go",
+					Src: `package foo
+
+import (
+	"encoding/json"
+	"os"
+)
+
+type Config struct{}
+
+func (Config) Validate() error {
+	return nil
+}
+
+func Foo(f *os.File, cfg Config) error {
+	defer f.Close()
+	json.Unmarshal(nil, &cfg)
+	_ = json.Unmarshal(nil, &cfg)
+	go cfg.Validate()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	close := f.Close
+	_ = close
+	return f.Close()
+}
+`,
+				},
+			},
+			cfg: nobadfuncs.Config{
+				Deny: []nobadfuncs.Rule{
+					{
+						ID:   "unchecked-error",
+						Refs: []string{"(*os.File).Close", "encoding/json.Unmarshal", "(github.com/palantir/go-nobadfuncs-test/foo.Config).Validate"},
+						When: "result-discarded",
+					},
+				},
+			},
+			want: func(testDir string) string {
+				return strings.Join([]string{
+					fmt.Sprintf("%s:15:10: the results of \"func (*os.File).Close() error\" must not be discarded. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:16:7: the results of \"func encoding/json.Unmarshal([]byte, any) error\" must not be discarded. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:17:11: the results of \"func encoding/json.Unmarshal([]byte, any) error\" must not be discarded. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+					fmt.Sprintf("%s:18:9: the results of \"func (github.com/palantir/go-nobadfuncs-test/foo.Config).Validate() error\" must not be discarded. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", path.Join(testDir, "foo/foo.go")),
+				}, "\n") + "\n"
+			},
+		},
+	} {
+		t.Run(currCase.name, func(t *testing.T) {
+			projectDir, err := ioutil.TempDir("", fmt.Sprintf("case-%d-", i))
+			require.NoError(t, err)
+
+			_, err = gofiles.Write(projectDir, append(currCase.specs, gofiles.GoFileSpec{
+				RelPath: "go.mod",
+				Src:     "module github.com/palantir/go-nobadfuncs-test",
+			}))
+			require.NoError(t, err, "Case %d: %s", i, currCase.name)
+
+			var got bytes.Buffer
+			// ignore return value since some cases will have errors (verifying output is sufficient)
+			_ = nobadfuncs.PrintBadRefs([]string{"./..."}, currCase.cfg, projectDir, nobadfuncs.Options{}, &got)
+
+			assert.Equal(t, currCase.want(projectDir), got.String(), "Case %d: %s\nOutput:\n%s", i, currCase.name, got.String())
+		})
+	}
+}
+
+func TestPresetAlias(t *testing.T) {
+	want, err := nobadfuncs.Config{Presets: []string{"crypto"}}.Resolve(t.TempDir())
+	require.NoError(t, err)
+	got, err := nobadfuncs.Config{Presets: []string{"unsafe-crypto"}}.Resolve(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,176 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// OutputFormat is the output format of the findings of a check.
+type OutputFormat string
+
+const (
+	// OutputFormatText prints each finding on its own line (see Finding.String).
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON prints the findings as a JSON array.
+	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatHTML prints a self-contained HTML report of the findings, including the findings that are
+	// whitelisted (see writeHTMLReport).
+	OutputFormatHTML OutputFormat = "html"
+	// OutputFormatSARIF prints the findings as a SARIF 2.1.0 log (see writeSARIFLog).
+	OutputFormatSARIF OutputFormat = "sarif"
+)
+
+// includesOwners returns true if the output format includes the owners of findings.
+func (f OutputFormat) includesOwners() bool {
+	return f == OutputFormatJSON || f == OutputFormatHTML || f == OutputFormatSARIF
+}
+
+// GroupBy specifies how findings are grouped in the output of a check.
+type GroupBy string
+
+const (
+	// GroupByOwner groups findings by the owners of their files as specified by Options.CodeOwners. A finding in a file
+	// with multiple owners is included in the group of each owner.
+	GroupByOwner GroupBy = "owner"
+)
+
+// unownedGroup is the name of the group of findings in files without owners in text output.
+const unownedGroup = "(unowned)"
+
+// OwnerFindings are the findings in the files owned by an owner.
+type OwnerFindings struct {
+	// Owner is the owner. Empty for the findings in files without owners.
+	Owner    string    `json:"owner"`
+	Findings []Finding `json:"findings"`
+}
+
+// UsesOwners returns true if the output specified by the options includes the owners of findings, which is the case if
+// the findings are grouped by owner or if the output format includes owners (such as OutputFormatJSON). Callers can use
+// this to only look for a CODEOWNERS file if it is required.
+func (o Options) UsesOwners() bool {
+	return o.GroupBy == GroupByOwner || o.Format.includesOwners()
+}
+
+// validateOutput returns an error if the output format or grouping specified by the provided options is not supported.
+func validateOutput(opts Options) error {
+	switch opts.Format {
+	case "", OutputFormatText, OutputFormatJSON, OutputFormatHTML, OutputFormatSARIF:
+	default:
+		return errors.Errorf("unsupported output format: %q", opts.Format)
+	}
+	switch opts.GroupBy {
+	case "":
+	case GroupByOwner:
+		if opts.CodeOwners == "" {
+			return errors.Errorf("grouping by owner requires a CODEOWNERS file")
+		}
+		if opts.Format != "" && opts.Format != OutputFormatText && opts.Format != OutputFormatJSON {
+			return errors.Errorf("grouping is not supported by the %s output format", opts.Format)
+		}
+	default:
+		return errors.Errorf("unsupported grouping: %q", opts.GroupBy)
+	}
+	return nil
+}
+
+// printFindings prints the provided findings in the format and grouping specified by the provided options, which must
+// be valid (see validateOutput).
+func printFindings(findings []Finding, opts Options, w io.Writer) error {
+	var groups []OwnerFindings
+	if opts.GroupBy == GroupByOwner {
+		groups = groupByOwner(findings)
+	}
+
+	switch opts.Format {
+	case "", OutputFormatText:
+		if opts.GroupBy == "" {
+			for _, finding := range findings {
+				_, _ = fmt.Fprintln(w, finding.String())
+			}
+			return nil
+		}
+		for i, group := range groups {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			owner := group.Owner
+			if owner == "" {
+				owner = unownedGroup
+			}
+			_, _ = fmt.Fprintf(w, "%s: %d finding%s\n", owner, len(group.Findings), plural(len(group.Findings)))
+			for _, finding := range group.Findings {
+				_, _ = fmt.Fprintln(w, finding.String())
+			}
+		}
+		return nil
+	case OutputFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if opts.GroupBy != "" {
+			return enc.Encode(groups)
+		}
+		if findings == nil {
+			findings = []Finding{}
+		}
+		return enc.Encode(findings)
+	case OutputFormatHTML:
+		return writeHTMLReport(findings, w)
+	case OutputFormatSARIF:
+		return writeSARIFLog(findings, w)
+	default:
+		return errors.Errorf("unsupported output format: %q", opts.Format)
+	}
+}
+
+// groupByOwner returns the findings grouped by owner in order of owner, followed by the findings in files without
+// owners.
+func groupByOwner(findings []Finding) []OwnerFindings {
+	byOwner := make(map[string][]Finding)
+	for _, finding := range findings {
+		if len(finding.Owners) == 0 {
+			byOwner[""] = append(byOwner[""], finding)
+		}
+		for _, owner := range finding.Owners {
+			byOwner[owner] = append(byOwner[owner], finding)
+		}
+	}
+	var out []OwnerFindings
+	for owner, ownerFindings := range byOwner {
+		out = append(out, OwnerFindings{
+			Owner:    owner,
+			Findings: ownerFindings,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if (out[i].Owner == "") != (out[j].Owner == "") {
+			return out[j].Owner == ""
+		}
+		return out[i].Owner < out[j].Owner
+	})
+	return out
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// codeOwnersLocations are the locations of the CODEOWNERS file relative to the root of a repository in the order in
+// which they are searched.
+var codeOwnersLocations = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+}
+
+// FindCodeOwners returns the path of the CODEOWNERS file of the repository that contains the provided directory. The
+// directory and each of its parents are searched for a ".github/CODEOWNERS", "CODEOWNERS" or "docs/CODEOWNERS" file up
+// to the root of the git repository (the first directory that contains a ".git" entry). Returns false if no file is
+// found.
+func FindCodeOwners(dir string) (string, bool) {
+	for curr := dir; ; {
+		for _, location := range codeOwnersLocations {
+			path := filepath.Join(curr, location)
+			if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+				return path, true
+			}
+		}
+		if _, err := os.Stat(filepath.Join(curr, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(curr)
+		if parent == curr {
+			return "", false
+		}
+		curr = parent
+	}
+}
+
+// codeOwners are the rules of a CODEOWNERS file.
+type codeOwners struct {
+	// root is the directory against which the patterns are matched.
+	root  string
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// loadCodeOwners reads the CODEOWNERS file at the provided path. The patterns are matched against paths relative to
+// the directory that contains the file or, if the file is in a ".github" or "docs" directory, its parent.
+func loadCodeOwners(path string) (codeOwners, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return codeOwners{}, errors.Wrapf(err, "failed to read CODEOWNERS file")
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return codeOwners{}, errors.Wrapf(err, "failed to determine absolute path of %s", path)
+	}
+	out := codeOwners{
+		root: filepath.Dir(absPath),
+	}
+	if base := filepath.Base(out.root); base == ".github" || base == "docs" {
+		out.root = filepath.Dir(out.root)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		re, err := codeOwnersRegexp(fields[0])
+		if err != nil {
+			return codeOwners{}, errors.Wrapf(err, "invalid pattern %q on line %d of %s", fields[0], lineNum, path)
+		}
+		rule := codeOwnersRule{
+			pattern: re,
+		}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			rule.owners = append(rule.owners, owner)
+		}
+		out.rules = append(out.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return codeOwners{}, errors.Wrapf(err, "failed to read CODEOWNERS file")
+	}
+	return out, nil
+}
+
+// owners returns the owners of the provided file, which are the owners of the last rule whose pattern matches it.
+// Returns nil if the file is not owned.
+func (c codeOwners) owners(filename string) []string {
+	rel, err := filepath.Rel(c.root, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].pattern.MatchString(rel) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// codeOwnersRegexp returns the regular expression for a CODEOWNERS pattern, which uses the syntax of gitignore
+// patterns: a pattern that does not contain a "/" (other than a trailing one) matches at any depth, a pattern that ends
+// with "/" only matches directories, "*" and "?" do not match "/", "**" matches any number of directories and a pattern
+// matches all of the files in the directories it matches unless its last element contains a wildcard.
+func codeOwnersRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return nil, errors.Errorf("pattern must not be empty")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored && !strings.Contains(pattern, "/") {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += len("**/") - 1
+		case strings.HasPrefix(pattern[i:], "**"):
+			sb.WriteString(".*")
+			i += len("**") - 1
+		case pattern[i] == '*':
+			sb.WriteString("[^/]*")
+		case pattern[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	lastElem := pattern[strings.LastIndex(pattern, "/")+1:]
+	switch {
+	case dirOnly:
+		sb.WriteString("/.*")
+	case !strings.ContainsAny(lastElem, "*?"):
+		sb.WriteString("(?:/.*)?")
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
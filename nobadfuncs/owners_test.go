@@ -0,0 +1,212 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintBadRefsCodeOwners(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src:     `module github.com/palantir/go-nobadfuncs-test`,
+		},
+		{
+			RelPath: ".github/CODEOWNERS",
+			Src: `# default owners
+*.go @org/core
+
+/cmd/ @org/cli @alice # cli
+internal/**/bar.go @bob
+/vendor/
+`,
+		},
+		{
+			RelPath: "cmd/main.go",
+			Src: `package main
+
+import "os"
+
+func main() {
+	os.Exit(1)
+}
+`,
+		},
+		{
+			RelPath: "internal/foo/foo.go",
+			Src: `package foo
+
+import "os"
+
+func Foo() {
+	os.Exit(1)
+}
+`,
+		},
+		{
+			RelPath: "internal/foo/bar.go",
+			Src: `package foo
+
+import "os"
+
+func Bar() {
+	os.Exit(1)
+}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	codeOwners, ok := nobadfuncs.FindCodeOwners(path.Join(projectDir, "internal", "foo"))
+	require.True(t, ok)
+	assert.Equal(t, path.Join(projectDir, ".github", "CODEOWNERS"), codeOwners)
+
+	cfg := nobadfuncs.Config{
+		Deny: []nobadfuncs.Rule{
+			{
+				ID:      "no-exit",
+				Refs:    []string{"os.Exit"},
+				Message: "do not exit",
+			},
+		},
+	}
+	mainFile := path.Join(projectDir, "cmd", "main.go")
+	barFile := path.Join(projectDir, "internal", "foo", "bar.go")
+	fooFile := path.Join(projectDir, "internal", "foo", "foo.go")
+
+	t.Run("owners of findings", func(t *testing.T) {
+		findings, err := nobadfuncs.Check([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{
+			CodeOwners: codeOwners,
+		})
+		require.NoError(t, err)
+		owners := make(map[string][]string)
+		for _, finding := range findings {
+			owners[finding.Pos.Filename] = finding.Owners
+		}
+		assert.Equal(t, map[string][]string{
+			mainFile: {"@org/cli", "@alice"},
+			barFile:  {"@bob"},
+			fooFile:  {"@org/core"},
+		}, owners)
+	})
+
+	t.Run("group by owner", func(t *testing.T) {
+		var got bytes.Buffer
+		err := nobadfuncs.PrintBadRefs([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{
+			CodeOwners: codeOwners,
+			GroupBy:    nobadfuncs.GroupByOwner,
+		}, &got)
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf(`@alice: 1 finding
+%s:6:5: do not exit
+
+@bob: 1 finding
+%s:6:5: do not exit
+
+@org/cli: 1 finding
+%s:6:5: do not exit
+
+@org/core: 1 finding
+%s:6:5: do not exit
+`, mainFile, barFile, mainFile, fooFile), got.String())
+	})
+
+	t.Run("JSON output", func(t *testing.T) {
+		var got bytes.Buffer
+		err := nobadfuncs.PrintBadRefs([]string{"./cmd"}, cfg, projectDir, nobadfuncs.Options{
+			CodeOwners: codeOwners,
+			Format:     nobadfuncs.OutputFormatJSON,
+		}, &got)
+		require.Error(t, err)
+		var findings []map[string]any
+		require.NoError(t, json.Unmarshal(got.Bytes(), &findings))
+		require.Len(t, findings, 1)
+		assert.Equal(t, "no-exit", findings[0]["ruleId"])
+		assert.Equal(t, []any{"@org/cli", "@alice"}, findings[0]["owners"])
+	})
+
+	t.Run("SARIF output", func(t *testing.T) {
+		var got bytes.Buffer
+		err := nobadfuncs.PrintBadRefs([]string{"./cmd"}, cfg, projectDir, nobadfuncs.Options{
+			CodeOwners: codeOwners,
+			Format:     nobadfuncs.OutputFormatSARIF,
+		}, &got)
+		require.Error(t, err)
+		var log struct {
+			Version string `json:"version"`
+			Runs    []struct {
+				Tool struct {
+					Driver struct {
+						Name  string           `json:"name"`
+						Rules []map[string]any `json:"rules"`
+					} `json:"driver"`
+				} `json:"tool"`
+				Results []map[string]any `json:"results"`
+			} `json:"runs"`
+		}
+		require.NoError(t, json.Unmarshal(got.Bytes(), &log))
+		assert.Equal(t, "2.1.0", log.Version)
+		require.Len(t, log.Runs, 1)
+		assert.Equal(t, "nobadfuncs", log.Runs[0].Tool.Driver.Name)
+		assert.Equal(t, []map[string]any{{"id": "no-exit"}}, log.Runs[0].Tool.Driver.Rules)
+		assert.Equal(t, []map[string]any{
+			{
+				"ruleId":  "no-exit",
+				"level":   "error",
+				"message": map[string]any{"text": "do not exit"},
+				"locations": []any{
+					map[string]any{
+						"physicalLocation": map[string]any{
+							"artifactLocation": map[string]any{"uri": "file://" + mainFile},
+							"region":           map[string]any{"startLine": float64(6), "startColumn": float64(5)},
+						},
+					},
+				},
+				"properties": map[string]any{
+					"ref":     "func os.Exit(int)",
+					"package": "github.com/palantir/go-nobadfuncs-test/cmd",
+					"owners":  []any{"@org/cli", "@alice"},
+				},
+			},
+		}, log.Runs[0].Results)
+	})
+
+	t.Run("group by owner is not supported by HTML output", func(t *testing.T) {
+		err := nobadfuncs.PrintBadRefs([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{
+			CodeOwners: codeOwners,
+			Format:     nobadfuncs.OutputFormatHTML,
+			GroupBy:    nobadfuncs.GroupByOwner,
+		}, &bytes.Buffer{})
+		assert.EqualError(t, err, "grouping is not supported by the html output format")
+	})
+
+	t.Run("group by owner requires CODEOWNERS file", func(t *testing.T) {
+		err := nobadfuncs.PrintBadRefs([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{
+			GroupBy: nobadfuncs.GroupByOwner,
+		}, &bytes.Buffer{})
+		assert.EqualError(t, err, "grouping by owner requires a CODEOWNERS file")
+	})
+}
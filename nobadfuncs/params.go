@@ -0,0 +1,83 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// enclosingParams records the parameters of the functions and function literals that enclose the identifiers of a
+// package.
+type enclosingParams struct {
+	info *types.Info
+	// funcs maps the position of each identifier in a function to the innermost function declaration or literal that
+	// contains it.
+	funcs map[token.Pos]ast.Node
+	// parents maps each function literal to the innermost function declaration or literal that contains it. Function
+	// literals that are not in a function (such as those in the values of package-level variables) are omitted.
+	parents map[ast.Node]ast.Node
+}
+
+func newEnclosingParams(pkg packageInfo) enclosingParams {
+	out := enclosingParams{
+		info:    pkg.Info,
+		funcs:   make(map[token.Pos]ast.Node),
+		parents: make(map[ast.Node]ast.Node),
+	}
+	for _, file := range pkg.Files {
+		ast.PreorderStack(file, nil, func(n ast.Node, stack []ast.Node) bool {
+			var fn ast.Node
+			for i := len(stack) - 1; i >= 0 && fn == nil; i-- {
+				switch stack[i].(type) {
+				case *ast.FuncDecl, *ast.FuncLit:
+					fn = stack[i]
+				}
+			}
+			if fn == nil {
+				return true
+			}
+			switch n := n.(type) {
+			case *ast.Ident:
+				out.funcs[n.Pos()] = fn
+			case *ast.FuncLit:
+				out.parents[n] = fn
+			}
+			return true
+		})
+	}
+	return out
+}
+
+// has returns true if the function or any of the function literals that contain the identifier at the provided
+// position has a parameter whose type, as printed by types.TypeString with fully qualified package paths, is typ.
+func (e enclosingParams) has(pos token.Pos, typ string) bool {
+	for fn := e.funcs[pos]; fn != nil; fn = e.parents[fn] {
+		var fields *ast.FieldList
+		switch fn := fn.(type) {
+		case *ast.FuncDecl:
+			fields = fn.Type.Params
+		case *ast.FuncLit:
+			fields = fn.Type.Params
+		}
+		for _, field := range fields.List {
+			if fieldType := e.info.TypeOf(field.Type); fieldType != nil && types.TypeString(fieldType, nil) == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// sarifLog is the subset of a SARIF 2.1.0 log that is written for the findings of a check.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID      string `json:"id"`
+	HelpURI string `json:"helpUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId,omitempty"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+	Properties   sarifProperties    `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification"`
+}
+
+// sarifProperties are the properties of a result, which contain the fields of a finding that SARIF does not define.
+type sarifProperties struct {
+	Ref     string   `json:"ref"`
+	Package string   `json:"package"`
+	Via     string   `json:"via,omitempty"`
+	Owners  []string `json:"owners,omitempty"`
+}
+
+// writeSARIFLog writes the provided findings to w as a SARIF 2.1.0 log with a single run. Each finding is a result whose
+// properties contain its reference, package, owners and (for findings of lower confidence) the way in which the object
+// is referenced. Whitelisted findings are results with an in-source suppression whose justification is the reason of
+// the "// OK: [reason]" comment.
+func writeSARIFLog(findings []Finding, w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "nobadfuncs",
+			InformationURI: "https://github.com/palantir/go-nobadfuncs",
+		}},
+		Results: []sarifResult{},
+	}
+	rules := make(map[string]bool)
+	for _, finding := range findings {
+		if finding.RuleID != "" && !rules[finding.RuleID] {
+			rules[finding.RuleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:      finding.RuleID,
+				HelpURI: finding.DocURL,
+			})
+		}
+		result := sarifResult{
+			RuleID:  finding.RuleID,
+			Level:   "error",
+			Message: sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: fileURI(finding.Pos.Filename)},
+				Region: sarifRegion{
+					StartLine:   finding.Pos.Line,
+					StartColumn: finding.Pos.Column,
+				},
+			}}},
+			Properties: sarifProperties{
+				Ref:     finding.Ref,
+				Package: finding.Package,
+				Via:     finding.Via,
+				Owners:  finding.Owners,
+			},
+		}
+		if finding.Suppression != "" {
+			result.Suppressions = []sarifSuppression{{
+				Kind:          "inSource",
+				Justification: finding.Suppression,
+			}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}); err != nil {
+		return errors.Wrapf(err, "failed to write SARIF log")
+	}
+	return nil
+}
+
+// fileURI returns the "file" URI of the provided path. Relative paths are returned as relative references.
+func fileURI(path string) string {
+	u := url.URL{Path: filepath.ToSlash(path)}
+	if filepath.IsAbs(path) {
+		u.Scheme = "file"
+	}
+	return u.String()
+}
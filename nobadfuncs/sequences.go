@@ -0,0 +1,323 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// mustCallResult is the value of SequenceRule.MustCall that specifies that the result itself must be called.
+const mustCallResult = "()"
+
+type sequenceMatcher struct {
+	SequenceRule
+	calls    refPatterns
+	notAfter refPatterns
+	// path are the selectors of MustCall. Empty if the result itself must be called.
+	path []string
+	pkgs pkgPatterns
+	msg  *template.Template
+}
+
+func newSequenceMatcher(rule SequenceRule) (sequenceMatcher, error) {
+	if (rule.MustCall == "") == (len(rule.NotAfter) == 0) {
+		return sequenceMatcher{}, errors.Errorf("exactly one of must-call and not-after must be specified")
+	}
+	if rule.Result < 0 {
+		return sequenceMatcher{}, errors.Errorf("result must be non-negative")
+	}
+	var path []string
+	if rule.MustCall != "" && rule.MustCall != mustCallResult {
+		path = strings.Split(rule.MustCall, ".")
+		for _, part := range path {
+			if !token.IsIdentifier(part) {
+				return sequenceMatcher{}, errors.Errorf("invalid must-call %q: must be %q or a selector such as %q", rule.MustCall, mustCallResult, "Body.Close")
+			}
+		}
+	}
+	calls, err := newRefPatterns(rule.Calls)
+	if err != nil {
+		return sequenceMatcher{}, err
+	}
+	notAfter, err := newRefPatterns(rule.NotAfter)
+	if err != nil {
+		return sequenceMatcher{}, err
+	}
+	pkgs, err := newPkgPatterns(rule.Packages)
+	if err != nil {
+		return sequenceMatcher{}, err
+	}
+	msg, err := newMessageTemplate(rule.Message)
+	if err != nil {
+		return sequenceMatcher{}, err
+	}
+	return sequenceMatcher{
+		SequenceRule: rule,
+		calls:        calls,
+		notAfter:     notAfter,
+		path:         path,
+		pkgs:         pkgs,
+		msg:          msg,
+	}, nil
+}
+
+// seqCall is a call of a function or method in a function body.
+type seqCall struct {
+	call *ast.CallExpr
+	// id is the identifier of the called function or method.
+	id  *ast.Ident
+	ref objRef
+	// fn is the innermost function declaration or literal that contains the call.
+	fn ast.Node
+	// parent is the parent node of the call.
+	parent ast.Node
+}
+
+// checkSequences returns the findings for the calls in the provided package that violate sequence rules.
+func (c *checker) checkSequences(pkg packageInfo, comments map[string]map[int]string, funcs enclosingFuncs) []Finding {
+	var rules []sequenceMatcher
+	for _, rule := range c.sequences {
+		if rule.pkgs.matches(pkg.Path, pkg.ModulePath) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	for _, file := range pkg.Files {
+		calls := fileCalls(pkg.Info, file)
+		for _, call := range calls {
+			for _, rule := range rules {
+				if !rule.calls.matches(call.ref) {
+					continue
+				}
+				msg, ok := rule.violation(pkg.Info, call, calls)
+				if !ok {
+					continue
+				}
+				pos := pkg.Fset.Position(call.id.Pos())
+				if isWhitelisted(comments, pos) {
+					break
+				}
+				finding := c.ruleFinding(call.ref.Sig, rule.ID, rule.Replacement, rule.DocURL, rule.msg, msg, MessageData{
+					Signature: call.ref.Sig,
+					Caller:    funcs.name(call.call.Pos()),
+					Package:   removeVendor(pkg.Path),
+				})
+				finding.Pos = pos
+				findings = append(findings, finding)
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// fileCalls returns the calls of package-level functions and methods in the function bodies of the provided file in
+// the order in which they appear.
+func fileCalls(info *types.Info, file *ast.File) []seqCall {
+	var calls []seqCall
+	ast.PreorderStack(file, nil, func(n ast.Node, stack []ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		var id *ast.Ident
+		switch fun := ast.Unparen(call.Fun).(type) {
+		case *ast.Ident:
+			id = fun
+		case *ast.SelectorExpr:
+			id = fun.Sel
+		default:
+			return true
+		}
+		ref, ok := newObjRef(info.Uses[id])
+		if !ok {
+			return true
+		}
+		if _, ok := ref.Obj.(*types.Func); !ok {
+			return true
+		}
+		fn := innermostFunc(stack)
+		if fn == nil {
+			return true
+		}
+		calls = append(calls, seqCall{
+			call:   call,
+			id:     id,
+			ref:    ref,
+			fn:     fn,
+			parent: stack[len(stack)-1],
+		})
+		return true
+	})
+	return calls
+}
+
+// innermostFunc returns the innermost function declaration or literal in the provided stack of nodes. Returns nil if
+// there is none.
+func innermostFunc(stack []ast.Node) ast.Node {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return stack[i]
+		}
+	}
+	return nil
+}
+
+// violation returns the default message for the provided call if it violates the rule. calls are all of the calls in
+// the file that contains the call.
+func (m sequenceMatcher) violation(info *types.Info, call seqCall, calls []seqCall) (string, bool) {
+	if len(m.notAfter) > 0 {
+		for _, prev := range calls {
+			if prev.call.Pos() >= call.call.Pos() {
+				break
+			}
+			if prev.fn == call.fn && m.notAfter.matches(prev.ref) {
+				return fmt.Sprintf("%q must not be called after %q.", call.ref.Sig, prev.ref.Sig), true
+			}
+		}
+		return "", false
+	}
+
+	msg := fmt.Sprintf("%s must be called on result %d of %q.", m.MustCall, m.Result, call.ref.Sig)
+	if len(m.path) == 0 {
+		msg = fmt.Sprintf("result %d of %q must be called or deferred.", m.Result, call.ref.Sig)
+	}
+	var result ast.Expr
+	switch parent := call.parent.(type) {
+	case *ast.ExprStmt:
+		// all results are discarded
+		return msg, true
+	case *ast.AssignStmt:
+		if len(parent.Rhs) != 1 || m.Result >= len(parent.Lhs) {
+			return "", false
+		}
+		result = parent.Lhs[m.Result]
+	case *ast.ValueSpec:
+		if len(parent.Values) != 1 || m.Result >= len(parent.Names) {
+			return "", false
+		}
+		result = parent.Names[m.Result]
+	default:
+		// the results are used by another expression, which takes responsibility for them
+		return "", false
+	}
+	id, ok := result.(*ast.Ident)
+	if !ok {
+		// the result is stored in a field or element, which may be used outside of the function
+		return "", false
+	}
+	if id.Name == "_" {
+		return msg, true
+	}
+	obj := info.ObjectOf(id)
+	if obj == nil || !declaredIn(obj, call.fn) {
+		// the result is assigned to a variable that may be used outside of the function, such as a named result
+		return "", false
+	}
+	if m.calledOrEscapes(info, call.fn, obj) {
+		return "", false
+	}
+	return msg, true
+}
+
+// declaredIn returns true if the provided object is declared in the body of the provided function declaration or literal.
+func declaredIn(obj types.Object, fn ast.Node) bool {
+	var body *ast.BlockStmt
+	switch fn := fn.(type) {
+	case *ast.FuncDecl:
+		body = fn.Body
+	case *ast.FuncLit:
+		body = fn.Body
+	}
+	return body != nil && body.Pos() <= obj.Pos() && obj.Pos() < body.End()
+}
+
+// calledOrEscapes returns true if the required call is made on the provided variable in the provided function (or in
+// any of the function literals it contains) or if the variable is used in a manner that makes another function
+// responsible for making the call, such as by being returned or passed as an argument.
+func (m sequenceMatcher) calledOrEscapes(info *types.Info, fn ast.Node, obj types.Object) bool {
+	found := false
+	ast.PreorderStack(fn, nil, func(n ast.Node, stack []ast.Node) bool {
+		if found {
+			return false
+		}
+		id, ok := n.(*ast.Ident)
+		if !ok || info.Uses[id] != obj {
+			return true
+		}
+		// match the selectors of the required call from the identifier outwards
+		var curr ast.Node = id
+		i := len(stack) - 1
+		matched := 0
+		for _, sel := range m.path {
+			parent, ok := stack[i].(*ast.SelectorExpr)
+			if !ok || parent.X != curr || parent.Sel.Name != sel {
+				break
+			}
+			curr = parent
+			i--
+			matched++
+		}
+		switch {
+		case matched == len(m.path):
+			if call, ok := stack[i].(*ast.CallExpr); ok && call.Fun == curr {
+				found = true
+			} else if matched == 0 {
+				found = escapes(id, stack[i])
+			}
+		case matched == 0:
+			found = escapes(id, stack[i])
+		}
+		// a partial selector (such as a field access) neither makes the call nor escapes
+		return true
+	})
+	return found
+}
+
+// escapes returns true if the provided identifier, whose parent is the provided node, is used as a value that may be
+// retained or used by other code.
+func escapes(id *ast.Ident, parent ast.Node) bool {
+	switch parent := parent.(type) {
+	case *ast.AssignStmt:
+		blank := true
+		for _, lhs := range parent.Lhs {
+			if lhs == id {
+				// the variable is assigned, not used
+				return false
+			}
+			if lhsID, ok := lhs.(*ast.Ident); !ok || lhsID.Name != "_" {
+				blank = false
+			}
+		}
+		// assigning the value to the blank identifier discards it
+		return !blank
+	case *ast.BinaryExpr, *ast.SelectorExpr:
+		// comparisons and field accesses do not retain the value
+		return false
+	}
+	return true
+}
@@ -79,7 +79,14 @@ func toTypeRemoveVendor(in types.Type) types.Type {
 	default:
 		panic(fmt.Errorf("unrecognized type: %v", in))
 	case *types.Alias:
-		return in
+		if typ.Obj().Pkg() == nil || typ.TypeArgs().Len() > 0 {
+			// universe aliases such as "any" do not have a package and generic aliases are kept as-is so that their
+			// type arguments are preserved.
+			return in
+		}
+		// an alias is printed using its name, so only its package needs to have the vendor directory removed.
+		typName := types.NewTypeName(typ.Obj().Pos(), pkgNoVendor(typ.Obj().Pkg()), typ.Obj().Name(), nil)
+		return types.NewAlias(typName, typ.Rhs())
 	case *types.TypeParam:
 		return in
 	case *types.Basic:
@@ -134,3 +141,73 @@ func newVarRemoveVendor(in *types.Var) *types.Var {
 	}
 	return types.NewVar(in.Pos(), in.Pkg(), in.Name(), toTypeRemoveVendor(in.Type()))
 }
+
+// toFuncUnalias returns a new version of the provided *types.Func where all of the aliases in its signature have been
+// replaced with the types they denote. Returns the provided function if its signature does not contain any aliases or if
+// the function is generic.
+func toFuncUnalias(in *types.Func) *types.Func {
+	sig, ok := in.Type().(*types.Signature)
+	if !ok || sig.TypeParams().Len() > 0 || sig.RecvTypeParams().Len() > 0 {
+		return in
+	}
+	newSig, changed := toTypeUnalias(sig)
+	if !changed {
+		return in
+	}
+	return types.NewFunc(in.Pos(), in.Pkg(), in.Name(), newSig.(*types.Signature))
+}
+
+// toTypeUnalias returns the provided type with all of the aliases in its structure replaced with the types they denote.
+// Named types are not traversed. Returns true if any aliases were replaced.
+func toTypeUnalias(in types.Type) (types.Type, bool) {
+	switch typ := in.(type) {
+	case *types.Alias:
+		out, _ := toTypeUnalias(types.Unalias(typ))
+		return out, true
+	case *types.Array:
+		elem, changed := toTypeUnalias(typ.Elem())
+		return types.NewArray(elem, typ.Len()), changed
+	case *types.Slice:
+		elem, changed := toTypeUnalias(typ.Elem())
+		return types.NewSlice(elem), changed
+	case *types.Pointer:
+		elem, changed := toTypeUnalias(typ.Elem())
+		return types.NewPointer(elem), changed
+	case *types.Map:
+		key, keyChanged := toTypeUnalias(typ.Key())
+		elem, elemChanged := toTypeUnalias(typ.Elem())
+		return types.NewMap(key, elem), keyChanged || elemChanged
+	case *types.Chan:
+		elem, changed := toTypeUnalias(typ.Elem())
+		return types.NewChan(typ.Dir(), elem), changed
+	case *types.Tuple:
+		if typ == nil {
+			return typ, false
+		}
+		var newVars []*types.Var
+		changed := false
+		for i := 0; i < typ.Len(); i++ {
+			v := typ.At(i)
+			newType, varChanged := toTypeUnalias(v.Type())
+			newVars = append(newVars, types.NewVar(v.Pos(), v.Pkg(), v.Name(), newType))
+			changed = changed || varChanged
+		}
+		return types.NewTuple(newVars...), changed
+	case *types.Signature:
+		var recv *types.Var
+		recvChanged := false
+		if typ.Recv() != nil {
+			var recvType types.Type
+			recvType, recvChanged = toTypeUnalias(typ.Recv().Type())
+			recv = types.NewVar(typ.Recv().Pos(), typ.Recv().Pkg(), typ.Recv().Name(), recvType)
+		}
+		params, paramsChanged := toTypeUnalias(typ.Params())
+		results, resultsChanged := toTypeUnalias(typ.Results())
+		if !recvChanged && !paramsChanged && !resultsChanged {
+			return in, false
+		}
+		return types.NewSignatureType(recv, nil, nil, params.(*types.Tuple), results.(*types.Tuple), typ.Variadic()), true
+	default:
+		return in, false
+	}
+}
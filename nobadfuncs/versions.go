@@ -0,0 +1,131 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"go/types"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/packages"
+)
+
+// versionRange is a set of alternative version ranges: a version is in the range if it satisfies all of the
+// constraints of any of the alternatives.
+type versionRange [][]versionConstraint
+
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+// newVersionRange parses the provided version range, which consists of alternatives separated by "||", each of which
+// consists of constraints separated by ",". A constraint is an operator ("<", "<=", ">", ">=", "=" or "!=") followed by
+// a semantic version, as in ">= v1.2.0, < v1.4.2 || >= v2.0.0, < v2.0.3". Returns nil if the range is empty.
+func newVersionRange(in string) (versionRange, error) {
+	if strings.TrimSpace(in) == "" {
+		return nil, nil
+	}
+	var out versionRange
+	for _, alternative := range strings.Split(in, "||") {
+		var constraints []versionConstraint
+		for _, constraintStr := range strings.Split(alternative, ",") {
+			constraintStr = strings.TrimSpace(constraintStr)
+			var constraint versionConstraint
+			for _, op := range []string{"<=", ">=", "!=", "<", ">", "="} {
+				if strings.HasPrefix(constraintStr, op) {
+					constraint = versionConstraint{
+						op:      op,
+						version: strings.TrimSpace(strings.TrimPrefix(constraintStr, op)),
+					}
+					break
+				}
+			}
+			if constraint.op == "" {
+				return nil, errors.Errorf("invalid version constraint %q: must begin with one of <, <=, >, >=, = or !=", constraintStr)
+			}
+			if !semver.IsValid(constraint.version) {
+				return nil, errors.Errorf("invalid version constraint %q: %q is not a valid semantic version", constraintStr, constraint.version)
+			}
+			constraints = append(constraints, constraint)
+		}
+		out = append(out, constraints)
+	}
+	return out, nil
+}
+
+// contains returns true if the provided version is in the range. A version that is not a valid semantic version (such as
+// the empty version of the main module) is not in any range.
+func (r versionRange) contains(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	for _, constraints := range r {
+		if allConstraints(constraints, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func allConstraints(constraints []versionConstraint, version string) bool {
+	for _, constraint := range constraints {
+		cmp := semver.Compare(version, constraint.version)
+		var ok bool
+		switch constraint.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// packageModules returns a map from each of the provided packages and their dependencies to the module that contains
+// it. Packages that are not in a module (such as the packages of the standard library) are not included.
+func packageModules(pkgs []*packages.Package) map[*types.Package]*packages.Module {
+	out := make(map[*types.Package]*packages.Module)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil && pkg.Module != nil {
+			out[pkg.Types] = pkg.Module
+		}
+	})
+	return out
+}
+
+// moduleVersion returns the path and the version in the build list of the provided module. If the module is replaced by
+// a specific version, that version is returned.
+func moduleVersion(module *packages.Module) (string, string) {
+	if module == nil {
+		return "", ""
+	}
+	if module.Replace != nil && module.Replace.Version != "" {
+		return module.Path, module.Replace.Version
+	}
+	return module.Path, module.Version
+}
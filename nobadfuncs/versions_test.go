@@ -0,0 +1,127 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintBadRefsVersions(t *testing.T) {
+	// dependency is provided using a local "replace" directive, which does not require network access.
+	prevValue := os.Getenv("GOFLAGS")
+	defer func() {
+		_ = os.Setenv("GOFLAGS", prevValue)
+	}()
+	err := os.Setenv("GOFLAGS", "-mod=mod")
+	require.NoError(t, err)
+
+	projectDir := t.TempDir()
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src: `module github.com/palantir/go-nobadfuncs-test
+
+require github.com/bar v1.4.1
+
+replace github.com/bar => ./bar
+`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import (
+	"github.com/bar"
+)
+
+func Foo() {
+	bar.Parse()
+	bar.Format()
+}
+`,
+		},
+		{
+			RelPath: "bar/go.mod",
+			Src:     `module github.com/bar`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src: `package bar
+
+func Parse() {}
+
+func Format() {}
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		versions string
+		want     string
+	}{
+		{
+			name:     "version in range",
+			versions: "< v1.4.2",
+			want:     fmt.Sprintf("%s:8:6: github.com/bar v1.4.1: func github.com/bar.Parse() has a known bug\n", path.Join(projectDir, "foo/foo.go")),
+		},
+		{
+			name:     "version in one of the alternatives",
+			versions: ">= v1.0.0, < v1.2.0 || >= v1.4.0, < v1.4.2",
+			want:     fmt.Sprintf("%s:8:6: github.com/bar v1.4.1: func github.com/bar.Parse() has a known bug\n", path.Join(projectDir, "foo/foo.go")),
+		},
+		{
+			name:     "version not in range",
+			versions: ">= v1.4.2",
+			want:     "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got bytes.Buffer
+			_ = nobadfuncs.PrintBadRefs([]string{"./..."}, nobadfuncs.Config{
+				Deny: []nobadfuncs.Rule{
+					{
+						Refs:     []string{"github.com/bar.Parse"},
+						Versions: tc.versions,
+						Message:  "{{.Module}} {{.Version}}: {{.Signature}} has a known bug",
+					},
+				},
+			}, projectDir, nobadfuncs.Options{}, &got)
+			assert.Equal(t, tc.want, got.String())
+		})
+	}
+
+	t.Run("invalid version range", func(t *testing.T) {
+		_, err := nobadfuncs.Check([]string{"./..."}, nobadfuncs.Config{
+			Deny: []nobadfuncs.Rule{
+				{
+					Refs:     []string{"github.com/bar.Parse"},
+					Versions: "~ v1.4.2",
+				},
+			},
+		}, projectDir, nobadfuncs.Options{})
+		assert.EqualError(t, err, `invalid deny rule at index 0: invalid version constraint "~ v1.4.2": must begin with one of <, <=, >, >=, = or !=`)
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// osvEntry is the subset of an entry of an OSV-format vulnerability database (as used by govulncheck) that is required
+// to determine the symbols that it affects.
+type osvEntry struct {
+	ID               string        `json:"id"`
+	Summary          string        `json:"summary"`
+	Details          string        `json:"details"`
+	Affected         []osvAffected `json:"affected"`
+	DatabaseSpecific struct {
+		URL string `json:"url"`
+	} `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced   string `json:"introduced"`
+			Fixed        string `json:"fixed"`
+			LastAffected string `json:"last_affected"`
+		} `json:"events"`
+	} `json:"ranges"`
+	EcosystemSpecific struct {
+		Imports []struct {
+			Path    string   `json:"path"`
+			Symbols []string `json:"symbols"`
+		} `json:"imports"`
+	} `json:"ecosystem_specific"`
+}
+
+// loadVulnDB returns the deny rules for the vulnerable symbols listed in the OSV entries in the provided directory. The
+// directory is searched recursively for ".json" files, except for the "index" directory of a govulncheck database. The
+// ID of each rule is the ID of the entry. Entries for the standard library and the toolchain are skipped because the
+// version of the standard library is not known.
+func loadVulnDB(dir string) ([]Rule, error) {
+	var paths []string
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "index" && path != dir {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".json") {
+			paths = append(paths, path)
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to read vulnerability database %s", dir)
+	}
+	sort.Strings(paths)
+
+	var rules []Rule
+	for _, path := range paths {
+		entryBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read vulnerability database entry")
+		}
+		var entry osvEntry
+		if err := json.Unmarshal(entryBytes, &entry); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse vulnerability database entry %s", path)
+		}
+		rules = append(rules, entry.rules()...)
+	}
+	return rules, nil
+}
+
+// rules returns the deny rules for the symbols affected by the entry.
+func (e osvEntry) rules() []Rule {
+	summary := e.Summary
+	if summary == "" {
+		summary = strings.Join(strings.Fields(e.Details), " ")
+	}
+	summary = strings.TrimSuffix(summary, ".") + "."
+	var rules []Rule
+	for _, affected := range e.Affected {
+		if affected.Package.Ecosystem != "Go" || affected.Package.Name == "stdlib" || affected.Package.Name == "toolchain" {
+			continue
+		}
+		versions, fixed := affected.versionRange()
+		if versions == "" {
+			continue
+		}
+		var refs []string
+		for _, imported := range affected.EcosystemSpecific.Imports {
+			if len(imported.Symbols) == 0 {
+				// all symbols of the package are affected
+				refs = append(refs, imported.Path)
+				continue
+			}
+			for _, symbol := range imported.Symbols {
+				if typeName, method, ok := strings.Cut(symbol, "."); ok {
+					refs = append(refs, fmt.Sprintf("(%s.%s).%s", imported.Path, typeName, method), fmt.Sprintf("(*%s.%s).%s", imported.Path, typeName, method))
+					continue
+				}
+				refs = append(refs, imported.Path+"."+symbol)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		msg := "{{.Signature}} is affected by {{.ID}} in {{.Module}} {{.Version}}: " + escapeTemplate(summary)
+		if fixed != "" {
+			msg += " Fixed in {{.Replacement}}."
+		}
+		if e.DatabaseSpecific.URL != "" {
+			msg += " See {{.DocURL}}."
+		}
+		rules = append(rules, Rule{
+			ID:          e.ID,
+			Refs:        refs,
+			Versions:    versions,
+			Message:     msg + " {{.Suffix}}",
+			Replacement: fixed,
+			DocURL:      e.DatabaseSpecific.URL,
+		})
+	}
+	return rules
+}
+
+// versionRange returns the version range (in the format of Rule.Versions) of the affected versions and the versions in
+// which the vulnerability is fixed. Returns an empty range if no versions are affected.
+func (a osvAffected) versionRange() (string, string) {
+	var alternatives, fixed []string
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		introduced := ""
+		for _, event := range r.Events {
+			switch {
+			case event.Introduced != "":
+				introduced = event.Introduced
+			case event.Fixed != "":
+				alternatives = append(alternatives, osvConstraints(introduced, "< v"+event.Fixed))
+				fixed = append(fixed, "v"+event.Fixed)
+				introduced = ""
+			case event.LastAffected != "":
+				alternatives = append(alternatives, osvConstraints(introduced, "<= v"+event.LastAffected))
+				introduced = ""
+			}
+		}
+		if introduced != "" {
+			alternatives = append(alternatives, osvConstraints(introduced, ""))
+		}
+	}
+	return strings.Join(alternatives, " || "), strings.Join(fixed, ", ")
+}
+
+// osvConstraints returns the constraints for the versions starting at the provided introduced version and within the
+// provided upper bound, which is a constraint on the fixed ("<") or last affected ("<=") version. An introduced version
+// of "0" indicates that all versions within the upper bound are affected and an empty upper bound indicates that all
+// versions starting at the introduced version are affected.
+func osvConstraints(introduced, upper string) string {
+	var constraints []string
+	if introduced != "" && introduced != "0" {
+		constraints = append(constraints, ">= v"+introduced)
+	}
+	if upper != "" {
+		constraints = append(constraints, upper)
+	}
+	if len(constraints) == 0 {
+		// lowest possible semantic version
+		return ">= v0.0.0-0"
+	}
+	return strings.Join(constraints, ", ")
+}
+
+// escapeTemplate returns a template that renders as the provided text.
+func escapeTemplate(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 Palantir Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nobadfuncs_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nmiyake/pkg/gofiles"
+	"github.com/palantir/go-nobadfuncs/nobadfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintBadRefsVulnDB(t *testing.T) {
+	// dependency is provided using a local "replace" directive, which does not require network access.
+	prevValue := os.Getenv("GOFLAGS")
+	defer func() {
+		_ = os.Setenv("GOFLAGS", prevValue)
+	}()
+	err := os.Setenv("GOFLAGS", "-mod=mod")
+	require.NoError(t, err)
+
+	projectDir := t.TempDir()
+	_, err = gofiles.Write(projectDir, []gofiles.GoFileSpec{
+		{
+			RelPath: "go.mod",
+			Src: `module github.com/palantir/go-nobadfuncs-test
+
+require github.com/bar v1.4.1
+
+replace github.com/bar => ./bar
+`,
+		},
+		{
+			RelPath: "foo/foo.go",
+			Src: `package foo
+
+import (
+	"github.com/bar"
+)
+
+func Foo() {
+	bar.Parse()
+	var c bar.Client
+	c.Do()
+	// OK: input is trusted
+	bar.Parse()
+	bar.Format()
+}
+`,
+		},
+		{
+			RelPath: "bar/go.mod",
+			Src:     `module github.com/bar`,
+		},
+		{
+			RelPath: "bar/bar.go",
+			Src: `package bar
+
+func Parse() {}
+
+func Format() {}
+
+type Client struct{}
+
+func (c *Client) Do() {}
+`,
+		},
+		{
+			RelPath: "vulndb/index/modules.json",
+			Src:     `[{"path": "github.com/bar"}]`,
+		},
+		{
+			RelPath: "vulndb/ID/GO-2024-0001.json",
+			Src: `{
+  "id": "GO-2024-0001",
+  "summary": "Denial of service in Parse and Client.Do",
+  "affected": [
+    {
+      "package": {"name": "github.com/bar", "ecosystem": "Go"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.4.2"}]}],
+      "ecosystem_specific": {"imports": [{"path": "github.com/bar", "symbols": ["Parse", "Client.Do"]}]}
+    }
+  ],
+  "database_specific": {"url": "https://pkg.go.dev/vuln/GO-2024-0001"}
+}`,
+		},
+		{
+			RelPath: "vulndb/ID/GO-2024-0002.json",
+			Src: `{
+  "id": "GO-2024-0002",
+  "summary": "Incorrect output in Format",
+  "affected": [
+    {
+      "package": {"name": "github.com/bar", "ecosystem": "Go"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.2.0"}, {"introduced": "1.5.0"}]}],
+      "ecosystem_specific": {"imports": [{"path": "github.com/bar", "symbols": ["Format"]}]}
+    }
+  ]
+}`,
+		},
+		{
+			RelPath: "vulndb/ID/GO-2024-0003.json",
+			Src: `{
+  "id": "GO-2024-0003",
+  "summary": "Risk accepted for all uses",
+  "affected": [
+    {
+      "package": {"name": "github.com/bar", "ecosystem": "Go"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}],
+      "ecosystem_specific": {"imports": [{"path": "github.com/bar"}]}
+    }
+  ]
+}`,
+		},
+		{
+			RelPath: "vulndb/ID/GO-2024-0004.json",
+			Src: `{
+  "id": "GO-2024-0004",
+  "summary": "Panic in Format",
+  "affected": [
+    {
+      "package": {"name": "github.com/bar", "ecosystem": "Go"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"last_affected": "1.4.0"}]}],
+      "ecosystem_specific": {"imports": [{"path": "github.com/bar", "symbols": ["Format"]}]}
+    }
+  ]
+}`,
+		},
+		{
+			RelPath: "nobadfuncs.yml",
+			Src: `vulndb: vulndb
+overrides:
+  - id: GO-2024-0003
+    disabled: true
+`,
+		},
+	})
+	require.NoError(t, err)
+
+	cfg, err := nobadfuncs.LoadConfig(path.Join(projectDir, "nobadfuncs.yml"))
+	require.NoError(t, err)
+
+	var got bytes.Buffer
+	_ = nobadfuncs.PrintBadRefs([]string{"./..."}, cfg, projectDir, nobadfuncs.Options{}, &got)
+
+	fooFile := path.Join(projectDir, "foo/foo.go")
+	want := strings.Join([]string{
+		fmt.Sprintf("%s:8:6: func github.com/bar.Parse() is affected by GO-2024-0001 in github.com/bar v1.4.1: Denial of service in Parse and Client.Do. Fixed in v1.4.2. See https://pkg.go.dev/vuln/GO-2024-0001. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", fooFile),
+		fmt.Sprintf("%s:10:4: func (*github.com/bar.Client).Do() is affected by GO-2024-0001 in github.com/bar v1.4.1: Denial of service in Parse and Client.Do. Fixed in v1.4.2. See https://pkg.go.dev/vuln/GO-2024-0001. Remove this reference or whitelist it by adding a comment of the form '// OK: [reason]' to the line before it.", fooFile),
+	}, "\n") + "\n"
+	assert.Equal(t, want, got.String())
+}